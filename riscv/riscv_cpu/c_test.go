@@ -59,7 +59,7 @@ func Test_C_CODE(t *testing.T) {
 		}
 	}
 
-	v, _ := sys.bus.Read(0x20000000, MEMORY_WIDTH_WORD)
+	v, _, _ := sys.bus.Read(0x20000000, MEMORY_WIDTH_WORD)
 	fmt.Printf("Final value at 0x20000000 = 0x%08X\n", v)
 	if v != 0x30040f00 {
 		t.Fatalf("Final value at 0x20000000 = 0x%08X; want 0x30040f00", v)
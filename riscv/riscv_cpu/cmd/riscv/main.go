@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"riscv"
+)
+
+func main() {
+	romPath := flag.String("rom", "", "path to a raw little-endian RV32 ROM image")
+	loadSnap := flag.String("load-snap", "", "resume from a snapshot written by -save-snap")
+	saveSnap := flag.String("save-snap", "", "write a snapshot here once the program terminates")
+	maxCycles := flag.Int("max-cycles", 100000, "stop after this many cycles if the program never terminates")
+	flag.Parse()
+
+	sys := riscv.NewRVI32System()
+
+	if *loadSnap != "" {
+		f, err := os.Open(*loadSnap)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "load-snap: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		if err := sys.Deserialize(f); err != nil {
+			fmt.Fprintf(os.Stderr, "load-snap: %v\n", err)
+			os.Exit(1)
+		}
+	} else if *romPath != "" {
+		data, err := os.ReadFile(*romPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "rom: %v\n", err)
+			os.Exit(1)
+		}
+		sys.LoadROM(bytesToWords(data))
+	}
+
+	sys.MaxCycles = uint64(*maxCycles)
+	for sys.State != riscv.TERMINATE {
+		if err := sys.Cycle(); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			break
+		}
+	}
+
+	if *saveSnap != "" {
+		f, err := os.Create(*saveSnap)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "save-snap: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		if err := sys.Serialize(f); err != nil {
+			fmt.Fprintf(os.Stderr, "save-snap: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+func bytesToWords(data []byte) []uint32 {
+	words := make([]uint32, 0, (len(data)+3)/4)
+	for i := 0; i < len(data); i += 4 {
+		var word uint32
+		for j := 0; j < 4 && i+j < len(data); j++ {
+			word |= uint32(data[i+j]) << (8 * j)
+		}
+		words = append(words, word)
+	}
+	return words
+}
@@ -1,14 +1,32 @@
 package riscv
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"riscv/csr"
 	. "riscv/pipeline"
 	. "riscv/system_interface"
+	"sync"
 )
 
+// Reservation is the load-reserved state LR.W/SC.W track: the address
+// reserved by the most recent LR.W, and whether it's still valid (cleared
+// by a matching SC.W or by any intervening store).
+type Reservation struct {
+	Addr  uint32
+	Valid bool
+}
+
+// ErrCycleLimit is returned by Cycle when RVI32System.Cycles exceeds
+// MaxCycles, so a fuzzer or CI harness can bound a runaway program instead
+// of looping forever.
+var ErrCycleLimit = errors.New("riscv: exceeded MaxCycles budget")
+
 const (
 	INSTRUCTION_FETCH = byte(iota)
 	DECODE
+	FUSE
 	EXECUTE
 	MEMORY_ACCESS
 	WRITE_BACK
@@ -23,13 +41,73 @@ type RVI32System struct {
 	rom     ROM_Device
 	regFile [32]RUint32
 
-	bus SystemInterface
+	bus   SystemInterface
+	UART  *UART_Device
+	Timer *Timer_Device
+
+	IF    *InstructionFetchStage
+	DE    *DecodeStage
+	Fuser *FuserStage
+	EX    *ExecuteStage
+	MA    *MemoryAccessStage
+	WB    *WriteBackStage
+
+	CSRs *csr.CSRFile
+
+	// cpuConfig holds feature toggles read by stage wiring set up once in
+	// NewRVI32System, such as whether Fuser's pass is active.
+	cpuConfig CPUConfig
+
+	trapPending bool
+	trapPC      uint32
+
+	// deValid/exValid/maValid/wbValid are the pipeline-register-boundary
+	// valid bits that replace State as the thing each stage's shouldStall
+	// closure gates on: deValid says IF's latch holds a real instruction for
+	// DE to decode this cycle, exValid says DE's latch holds one for
+	// Fuser/EX, maValid says EX's latch holds one for MA, wbValid says MA's
+	// latch holds one for WB. Cycle updates all four every cycle so IF/DE/
+	// EX/MA/WB can all run concurrently, one instruction per stage.
+	deValid bool
+	exValid bool
+	maValid bool
+	wbValid bool
+
+	// frozen holds IF/Fuser/DE/EX in place for a cycle MA can't drain
+	// (still waiting on a multi-cycle bus transaction): a structural stall
+	// that backs up the whole front end rather than letting EX clobber a
+	// result MA hasn't consumed yet. MA and WB are never gated by frozen;
+	// they keep draining (or sitting idle) on their own IsStalled/valid
+	// bits.
+	frozen bool
+
+	// halted is set once IF fetches the all-zero end-of-program word. It
+	// stops new instructions from entering the pipeline while the ones
+	// already in flight drain through to WRITE_BACK, instead of cutting
+	// them off the way the abrupt sysExit-driven TERMINATE in
+	// cpu_syscall.go does.
+	halted bool
+
+	// Cycles counts clock cycles spent so far: one per Cycle() call, plus
+	// whatever EX.GetExecuteCycles()/MA.GetMemoryAccessCycles() charge
+	// for branch bubbles, shifts, and load/store latency.
+	Cycles uint64
+	// MaxCycles bounds Cycles; 0 means unbounded. Once exceeded, Cycle()
+	// sets State to TERMINATE and returns ErrCycleLimit.
+	MaxCycles uint64
 
-	IF *InstructionFetchStage
-	DE *DecodeStage
-	EX *ExecuteStage
-	MA *MemoryAccessStage
-	WB *WriteBackStage
+	syscalls map[uint32]syscallEntry
+	// ExitCode holds the status passed to SYS_EXIT, valid once the default
+	// sysExit handler has set State to TERMINATE.
+	ExitCode int32
+
+	// Reservation is the LR.W/SC.W reservation for this hart.
+	Reservation Reservation
+	// amoLock serializes an AMO's read-modify-write. A no-op for this
+	// single-hart simulator today, but holding it around every AMO means a
+	// future multi-hart RVI32System can share one bus without re-plumbing
+	// MemoryAccessStage.
+	amoLock sync.Mutex
 }
 
 func NewRVI32System() *RVI32System {
@@ -43,17 +121,48 @@ func NewRVI32System() *RVI32System {
 	// sys.regFile = [32]Register32{}
 
 	sys.bus = *NewSystemInterface(&sys.rom, &sys.ram)
+	sys.UART = NewUARTDevice()
+	sys.Timer = NewTimerDevice()
+	sys.bus.RegisterDevice("UART", MEMORY_MAP_UART_START, MEMORY_MAP_UART_SIZE, sys.UART)
+	sys.bus.RegisterDevice("TIMER", MEMORY_MAP_TIMER_START, MEMORY_MAP_TIMER_SIZE, sys.Timer)
+	sys.CSRs = csr.NewCSRFile()
+	sys.registerDefaultSyscalls()
 
+	// getBranchAddressValid runs before getBranchAddress every time
+	// InstructionFetchStage.Compute redirects (it also calls getBranchAddress
+	// a second time afterward, for the trace message). Both must agree on
+	// whether this redirect is the trap or the branch, so snapshot that
+	// decision once in redirectPC/redirectValid when validity is checked,
+	// and have getBranchAddress just replay it — reading sys.trapPending
+	// again there would see it already cleared by the one-shot consume
+	// below and fall through to the stale branch address instead.
+	var redirectPC uint32
+	var redirectValid bool
 	ifsParams := NewInstructionFetchParams(
 		&sys.bus,
 		func() uint32 {
-			return uint32(sys.EX.GetExecutionValuesOut().BranchAddress)
+			return redirectPC
 		},
 		func() bool {
-			return sys.EX.GetExecutionValuesOut().BranchValid
+			if sys.trapPending {
+				redirectPC = sys.trapPC
+				redirectValid = true
+			} else if sys.maValid && sys.EX.GetExecutionValuesOut().BranchValid {
+				// sys.maValid (not just "did EX run last cycle") gates this:
+				// a branch whose own EX.Compute squashed it out of MA (a
+				// trap from an older instruction arrived the same cycle)
+				// must not get to redirect fetch here just because its
+				// stale latch still shows BranchValid.
+				redirectPC = uint32(sys.EX.GetExecutionValuesOut().BranchAddress)
+				redirectValid = true
+			} else {
+				redirectValid = false
+			}
+			sys.trapPending = false // one-shot: consumed by the fetch it redirects
+			return redirectValid
 		},
 		func() bool {
-			return sys.State != INSTRUCTION_FETCH
+			return sys.frozen || sys.halted
 		},
 	)
 	sys.IF = NewInstructionFetchStage(ifsParams)
@@ -61,17 +170,34 @@ func NewRVI32System() *RVI32System {
 	decodeParams := NewDecodeParams(
 		&sys.regFile,
 		func() bool {
-			return sys.State != DECODE
+			return sys.frozen || !sys.deValid
 		},
 		sys.IF.GetFetchValuesOut,
 	)
 	sys.DE = NewDecodeStage(decodeParams)
 
-	executeParams := NewExecuteParams(
+	fuserParams := NewFuserParams(
+		&sys.cpuConfig,
 		func() bool {
-			return sys.State != EXECUTE
+			return sys.frozen || !sys.exValid
 		},
 		sys.DE.GetDecodedValuesOut,
+	)
+	sys.Fuser = NewFuserStage(fuserParams)
+
+	executeParams := NewExecuteParams(
+		func() bool {
+			if sys.frozen || !sys.exValid {
+				return true
+			}
+			return sys.cpuConfig.EnableFusion && !sys.Fuser.ReadyValid()
+		},
+		func() DecodedValues {
+			if sys.cpuConfig.EnableFusion {
+				return sys.Fuser.GetFusedValuesOut()
+			}
+			return sys.DE.GetDecodedValuesOut()
+		},
 		&sys.regFile,
 	)
 	sys.EX = NewExecuteStage(executeParams)
@@ -79,35 +205,90 @@ func NewRVI32System() *RVI32System {
 	memoryAccessParams := NewMemoryAccessParams(
 		sys.bus,
 		func() bool {
-			return sys.State != MEMORY_ACCESS
+			return !sys.maValid
 		},
 		sys.EX.GetExecutionValuesOut,
 	)
 	sys.MA = NewMemoryAccessStage(memoryAccessParams)
+	sys.EX.SetForwardSource(sys.MA.GetForwardOut)
+	sys.MA.SetReservation(
+		func(addr uint32, valid bool) {
+			sys.Reservation = Reservation{Addr: addr, Valid: valid}
+		},
+		func(addr uint32) bool {
+			return sys.Reservation.Valid && sys.Reservation.Addr == addr
+		},
+	)
+	sys.MA.SetAmoLock(sys.amoLock.Lock, sys.amoLock.Unlock)
 
 	writeBackParams := NewWriteBackParams(
 		&sys.regFile,
 		func() bool {
-			return sys.State != WRITE_BACK
+			return !sys.wbValid
 		},
 		sys.MA.GetMemoryAccessValuesOut,
 	)
 	sys.WB = NewWriteBackStage(writeBackParams)
+	sys.EX.SetForwardSourceWB(sys.WB.GetForwardOut)
 
 	return sys
 }
 
-func (sys *RVI32System) Compute() {
-	sys.IF.Compute()
-	sys.DE.Compute()
-	sys.EX.Compute()
-	sys.MA.Compute()
-	sys.WB.Compute()
+// LoadROM copies a little-endian RV32 program into ROM, for callers outside
+// this package that can't reach the unexported rom field directly.
+func (sys *RVI32System) LoadROM(words []uint32) {
+	sys.rom.Load(words)
+}
+
+// SetRVFISink attaches a trace sink that receives one RetireRecord per
+// committed instruction, mirroring the interface used by the Sail RISC-V
+// model for co-simulation and formal checking.
+func (sys *RVI32System) SetRVFISink(sink RVFISink) {
+	sys.WB.SetRVFISink(sink)
+}
+
+// SetCPUConfig installs feature toggles that don't belong to any one
+// pipeline stage, such as EnableFusion, mirroring SetCycleCost.
+func (sys *RVI32System) SetCPUConfig(config CPUConfig) {
+	sys.cpuConfig = config
+}
+
+// SetTracer attaches a Tracer to every stage that previously wrote its
+// progress straight to stdout via fmt.Print*, so the simulator can be run
+// quietly, with JSON-lines output, or with a bounded post-mortem ring
+// buffer, without recompiling.
+func (sys *RVI32System) SetTracer(tracer Tracer) {
+	sys.IF.SetTracer(tracer)
+	sys.DE.SetTracer(tracer)
+	sys.EX.SetTracer(tracer)
+	sys.MA.SetTracer(tracer)
+	sys.WB.SetTracer(tracer)
+}
+
+// SetDebugger attaches a Debugger that gets control whenever a fetch-stage
+// breakpoint fires, e.g. a GDBStub driving a gdb-multiarch session. Pass
+// nil to go back to never stopping.
+func (sys *RVI32System) SetDebugger(debugger Debugger) {
+	sys.IF.SetDebugger(debugger)
+}
+
+// AddBreakpoint/RemoveBreakpoint arm and disarm a PC breakpoint in the
+// fetch stage, the software-breakpoint half of the PSX-style debug
+// facility this package borrows from (BPC/BPCM). Watchpoints on the data
+// bus are configured directly via sys.bus.AddReadWatchpoint/
+// AddWriteWatchpoint.
+func (sys *RVI32System) AddBreakpoint(pc uint32) {
+	sys.IF.AddBreakpoint(pc)
+}
+
+func (sys *RVI32System) RemoveBreakpoint(pc uint32) {
+	sys.IF.RemoveBreakpoint(pc)
 }
 
 func (sys *RVI32System) LatchNext() {
 	sys.IF.LatchNext()
 	sys.DE.LatchNext()
+	sys.Fuser.LatchNext()
 	sys.EX.LatchNext()
 	sys.MA.LatchNext()
 	sys.WB.LatchNext()
@@ -117,26 +298,270 @@ func (sys *RVI32System) LatchNext() {
 	}
 }
 
-func (sys *RVI32System) Cycle() {
-	sys.Compute()
+// handleTraps runs the SYSTEM-opcode instruction currently decoded (CSR
+// access, ECALL, EBREAK, MRET) once it reaches EXECUTE, and arms
+// trapPending so InstructionFetchStage redirects to mtvec/mepc on the next
+// fetch via the same branch-taken path used for JAL/JALR/branches.
+func (sys *RVI32System) handleTraps() {
+	decoded := sys.DE.GetDecodedValuesOut()
+	if !decoded.IsSystemOp() {
+		return
+	}
+	faultPC := decoded.PcPlus4() - 4
+
+	switch {
+	case decoded.IsCsrOp():
+		csrAddr := uint32(decoded.Imm32())
+		old, ok := sys.CSRs.Read(csrAddr)
+		if !ok {
+			sys.trapPC = sys.CSRs.EnterTrap(faultPC, csr.CauseIllegalInstruction, decoded.Insn())
+			sys.trapPending = true
+			return
+		}
+		if decoded.Rd() != 0 {
+			sys.regFile[decoded.Rd()].SetN(old)
+		}
+
+		// rs1V comes from EX's forwarded operand, not decoded.Rs1V(): DE
+		// latched rs1 a cycle before EX runs, so a value the immediately
+		// preceding instruction produced (still in MA, not yet in regFile)
+		// would otherwise read stale.
+		rs1V, _ := sys.EX.GetOperandsOut()
+
+		var next uint32
+		switch decoded.Func3() {
+		case FUNC3_CSRRW:
+			next = rs1V
+		case FUNC3_CSRRS:
+			next = old | rs1V
+		case FUNC3_CSRRC:
+			next = old &^ rs1V
+		case FUNC3_CSRRWI:
+			next = uint32(decoded.Rs1Addr()) // rs1 field carries the 5-bit zimm
+		case FUNC3_CSRRSI:
+			next = old | uint32(decoded.Rs1Addr())
+		case FUNC3_CSRRCI:
+			next = old &^ uint32(decoded.Rs1Addr())
+		}
+		sys.CSRs.Write(csrAddr, next)
+
+	case decoded.IsEcall():
+		if entry, ok := sys.syscalls[sys.regFile[REG_A7].GetN()]; ok {
+			ret, err := entry.fn(sys,
+				sys.regFile[REG_A0].GetN(), sys.regFile[REG_A1].GetN(), sys.regFile[REG_A2].GetN(),
+				sys.regFile[REG_A3].GetN(), sys.regFile[REG_A4].GetN(), sys.regFile[REG_A5].GetN(),
+				sys.regFile[REG_A6].GetN())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "syscall %s error: %v\n", entry.name, err)
+			}
+			sys.regFile[REG_A0].SetN(ret)
+			break
+		}
+		sys.trapPC = sys.CSRs.EnterTrap(faultPC, csr.CauseECallFromMMode, 0)
+		sys.trapPending = true
+
+	case decoded.IsEbreak():
+		sys.trapPC = sys.CSRs.EnterTrap(faultPC, csr.CauseBreakpoint, faultPC)
+		sys.trapPending = true
+
+	case decoded.IsMret():
+		sys.trapPC = sys.CSRs.MRET()
+		sys.trapPending = true
+	}
+}
+
+// checkInterrupts arms trapPending for the timer interrupt the same way
+// handleTraps arms it for a synchronous ECALL/EBREAK/CSR trap, but sourced
+// from Timer's asynchronous Pending line instead of the decoded
+// instruction. Only checked at an INSTRUCTION_FETCH boundary, so an
+// interrupt never preempts an instruction already mid-pipeline.
+func (sys *RVI32System) checkInterrupts() {
+	if sys.CSRs.Mstatus&csr.MstatusMIE == 0 || sys.CSRs.Mie&csr.MieMTIE == 0 {
+		return
+	}
+	if !sys.Timer.Pending() {
+		return
+	}
+	sys.CSRs.Mip |= csr.MipMTIP
+	sys.trapPC = sys.CSRs.EnterTrap(sys.IF.GetFetchValuesOut().Pc(), csr.CauseMachineTimerInterrupt, 0)
+	sys.trapPending = true
+}
+
+// handleIllegalInstruction arms trapPending for an opcode DecodeStage
+// couldn't classify, or an ALU encoding ExecuteStage's LookupDesc couldn't
+// resolve, the same redirect-on-next-fetch mechanism handleTraps uses for a
+// recognized SYSTEM instruction. Sourced from EX's latch, like
+// handleMemFault, since DE has already moved on to a newer instruction by
+// the time Cycle checks this.
+func (sys *RVI32System) handleIllegalInstruction() {
+	executed := sys.EX.GetExecutionValuesOut()
+	faultPC := executed.PcPlus4() - 4
+	sys.trapPC = sys.CSRs.EnterTrap(faultPC, csr.CauseIllegalInstruction, executed.Insn())
+	sys.trapPending = true
+}
+
+// handleIllegalMemOp arms trapPending for a reserved load/store func3 or
+// unrecognized AMO tag MemoryAccessStage couldn't execute at all, the same
+// redirect-on-next-fetch mechanism handleMemFault uses for a misaligned or
+// unmapped access that the bus rejected.
+func (sys *RVI32System) handleIllegalMemOp() {
+	if !sys.MA.IllegalOp() {
+		return
+	}
+	executed := sys.EX.GetExecutionValuesOut()
+	faultPC := executed.PcPlus4() - 4
+	sys.trapPC = sys.CSRs.EnterTrap(faultPC, csr.CauseIllegalInstruction, executed.Insn())
+	sys.trapPending = true
+}
+
+// handleMemFault arms trapPending for a misaligned/unmapped load or store
+// MemoryAccessStage surfaced, the same redirect-on-next-fetch mechanism
+// handleTraps uses, but sourced from the bus instead of the decoded
+// instruction. faultPC comes from EX's latch (what MA is acting on this
+// cycle), not DE's (which has already moved on to a newer instruction by
+// the time its result reaches MA). err distinguishes a genuine misaligned
+// address (wraps ErrMisalignedAccess) from an access to unmapped memory,
+// so the two report distinct CSR causes instead of both claiming
+// misalignment.
+func (sys *RVI32System) handleMemFault() {
+	addr, isStore, err, ok := sys.MA.MemFault()
+	if !ok {
+		return
+	}
+	executed := sys.EX.GetExecutionValuesOut()
+	faultPC := executed.PcPlus4() - 4
+
+	misaligned := errors.Is(err, ErrMisalignedAccess)
+	var cause uint32
+	switch {
+	case misaligned && isStore:
+		cause = csr.CauseStoreAddressMisaligned
+	case misaligned:
+		cause = csr.CauseLoadAddressMisaligned
+	case isStore:
+		cause = csr.CauseStoreAccessFault
+	default:
+		cause = csr.CauseLoadAccessFault
+	}
+	sys.trapPC = sys.CSRs.EnterTrap(faultPC, cause, addr)
+	sys.trapPending = true
+}
+
+// Cycle advances every stage by one clock: IF, DE, Fuser, EX, MA and WB each
+// work on their own instruction concurrently, the way a real 5-stage
+// pipeline overlaps IF/ID/EX/MEM/WB instead of running one instruction to
+// completion before fetching the next. MA is evaluated first so its
+// IsStalled (a multi-cycle bus transaction still in flight) can freeze the
+// front end (IF/Fuser/DE/EX) for the cycle before anything downstream of it
+// computes; MA and WB are never frozen, so a stalled MA still lets WB drain
+// whatever it latched last cycle.
+func (sys *RVI32System) Cycle() error {
+	maValidThisCycle := sys.maValid
+	deValidThisCycle := sys.deValid
+	exValidThisCycle := sys.exValid
+
+	sys.MA.Compute()
+	maBusy := maValidThisCycle && sys.MA.IsStalled()
+	sys.frozen = maBusy
+	if maValidThisCycle && !maBusy {
+		sys.handleMemFault()
+		sys.handleIllegalMemOp()
+		sys.Cycles += sys.MA.GetMemoryAccessCycles()
+	}
+
+	// WB.Compute must run against last cycle's wbValid (MA's latch, as of
+	// the end of the previous cycle, via GetMemoryAccessValuesOut's .Get())
+	// before it's overwritten with this cycle's MA result below — the same
+	// one-cycle-later relationship deValid/exValid/maValid keep by only
+	// being reassigned once every stage this cycle has already run.
+	sys.WB.Compute()
+	sys.wbValid = maValidThisCycle && !maBusy
+
+	if !sys.frozen {
+		sys.Fuser.Compute()
+		fuserReady := true
+		if sys.cpuConfig.EnableFusion {
+			fuserReady = sys.Fuser.ReadyValid()
+		}
+		exRan := exValidThisCycle && fuserReady
+
+		// redirected reflects an OLDER instruction's redirect: a mem fault
+		// handleMemFault already armed above, or the branch/trap an
+		// instruction now sitting in MA resolved last cycle (maValidThisCycle,
+		// not exRan, gates the latter — it must not fire just because EX's
+		// stale latch still shows BranchValid for a branch that itself got
+		// squashed out of MA). Computed before EX.Compute() so it can also
+		// gate whether THIS cycle's EX result gets to raise its own trap: a
+		// bubble fetched before the older redirect was known is wrong-path
+		// and must not fault on its way to being squashed (see sys.maValid
+		// below) — otherwise an unconditional self-branch like JAL(0,0)
+		// spuriously traps on the garbage bytes speculatively fetched past
+		// it before the branch resolves. Safe to read this early: it's EX's
+		// latched (Get) output, unaffected by EX.Compute() either way, and
+		// must run before sys.trapPending's one-shot consume in IF's
+		// redirect closure below.
+		redirected := sys.trapPending || (maValidThisCycle && sys.EX.GetExecutionValuesOut().BranchValid)
+
+		sys.EX.Compute()
+		if exRan {
+			if redirected {
+				// Wrong-path: an older instruction already in MA resolved
+				// its own branch/trap this cycle, so exRan's result is
+				// about to be squashed from ever reaching MA and must not
+				// raise an illegal-instruction/SYSTEM trap of its own.
+			} else if sys.EX.IsIllegalOp() {
+				sys.handleIllegalInstruction()
+			} else {
+				sys.handleTraps()
+			}
+			sys.Cycles += sys.EX.GetExecuteCycles()
+		}
+
+		sys.DE.Compute()
+
+		ifProducedNewValid := false
+		if !sys.halted {
+			sys.checkInterrupts()
+			redirected = redirected || sys.trapPending
+			sys.IF.Compute()
+			if faultPC, ok := sys.IF.IllegalFetch(); ok {
+				// trapPending alone is enough here: it redirects the fetch
+				// that runs next Cycle() call. Folding it into redirected
+				// too would squash maValid/exValid for the EX/DE
+				// instructions already in flight this cycle — instructions
+				// strictly older than (and unrelated to) the one IF just
+				// failed to fetch.
+				sys.trapPC = sys.CSRs.EnterTrap(faultPC, csr.CauseIllegalInstruction, 0)
+				sys.trapPending = true
+			} else if !sys.IF.IsStalled() {
+				if sys.IF.IsEndOfProgram() {
+					sys.halted = true
+				} else {
+					ifProducedNewValid = true
+				}
+			}
+		}
+
+		sys.maValid = exRan && !redirected
+		sys.exValid = deValidThisCycle && !redirected
+		sys.deValid = ifProducedNewValid
+	}
+
 	sys.LatchNext()
 
-	switch sys.State {
-	case INSTRUCTION_FETCH:
-		sys.State = DECODE
-	case DECODE:
-		sys.State = EXECUTE
-	case EXECUTE:
-		sys.State = MEMORY_ACCESS
-	case MEMORY_ACCESS:
-		sys.State = WRITE_BACK
-	case WRITE_BACK:
-		sys.State = INSTRUCTION_FETCH
+	sys.Cycles++
+	sys.Timer.Tick()
+	sys.CSRs.SyncTimer(sys.Timer.Mtime(), sys.Timer.Mtimecmp())
+
+	if sys.MaxCycles > 0 && sys.Cycles > sys.MaxCycles {
+		sys.State = TERMINATE
+		return ErrCycleLimit
 	}
 
-	if sys.IF.GetFetchValuesOut().Instruction == 0 {
+	if sys.halted && !sys.deValid && !sys.exValid && !sys.maValid && !sys.wbValid && sys.State != TERMINATE {
 		sys.State = TERMINATE
 		fmt.Print("\n---- TERMINATE ----\n")
-		return
 	}
+
+	return nil
 }
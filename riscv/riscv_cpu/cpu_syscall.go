@@ -0,0 +1,120 @@
+package riscv
+
+import (
+	"os"
+
+	. "riscv/system_interface"
+)
+
+// Syscall is a host-implemented routine invoked on ECALL when x17 (a7)
+// holds its registered number, the same dispatch-by-number shape SBPF uses
+// for its eBPF VM's syscalls map[uint32]Syscall. a0-a6 mirror the argument
+// registers x10-x16; the returned value is written back into x10, the
+// RISC-V return-value register, once fn returns.
+type Syscall func(sys *RVI32System, a0, a1, a2, a3, a4, a5, a6 uint32) (ret uint32, err error)
+
+// syscallEntry pairs a handler with the name it was registered under, so a
+// failed syscall can be reported against something more useful than a bare
+// number.
+type syscallEntry struct {
+	name string
+	fn   Syscall
+}
+
+// Linux RV32 syscall numbers for the subset of semihosting calls shipped by
+// default.
+const (
+	SYS_READ  = 63
+	SYS_WRITE = 64
+	SYS_EXIT  = 93
+	SYS_BRK   = 214
+)
+
+// RISC-V calling convention: a0-a6 are x10-x16, a7 is x17.
+const (
+	REG_A0 = 10
+	REG_A1 = 11
+	REG_A2 = 12
+	REG_A3 = 13
+	REG_A4 = 14
+	REG_A5 = 15
+	REG_A6 = 16
+	REG_A7 = 17
+)
+
+// Register installs or replaces the handler invoked on ECALL when a7 equals
+// num, overriding any default implementation with the same number. name is
+// used only for diagnostics (a failed syscall's error message).
+func (sys *RVI32System) Register(num uint32, name string, fn Syscall) {
+	if sys.syscalls == nil {
+		sys.syscalls = map[uint32]syscallEntry{}
+	}
+	sys.syscalls[num] = syscallEntry{name: name, fn: fn}
+}
+
+// registerDefaultSyscalls installs the minimal semihosting set every
+// RVI32System starts with, so a guest program can print to stdout, read
+// from stdin, grow its heap, and exit with a real status instead of the
+// caller having to poke a magic word into RAM and inspect it after the
+// fact.
+func (sys *RVI32System) registerDefaultSyscalls() {
+	sys.Register(SYS_WRITE, "write", sysWrite)
+	sys.Register(SYS_EXIT, "exit", sysExit)
+	sys.Register(SYS_READ, "read", sysRead)
+	sys.Register(SYS_BRK, "brk", sysBrk)
+}
+
+// sysWrite implements write(fd a0, buf a1, count a2): fd is ignored, every
+// write goes to stdout. buf/count describe a byte range read from the
+// system bus one word at a time, mirroring how MemoryAccessStage performs
+// loads.
+func sysWrite(sys *RVI32System, a0, a1, a2, a3, a4, a5, a6 uint32) (uint32, error) {
+	buf := a1
+	count := a2
+
+	data := make([]byte, 0, count)
+	for i := uint32(0); uint32(len(data)) < count; i += 4 {
+		var word uint32
+		var err error
+		for {
+			var ready bool
+			word, ready, err = sys.bus.Read(buf+i, MEMORY_WIDTH_WORD)
+			if err != nil {
+				return 0, err
+			}
+			if ready {
+				break
+			}
+		}
+		for b := 0; b < 4 && uint32(len(data)) < count; b++ {
+			data = append(data, byte(word>>(8*b)))
+		}
+	}
+
+	n, err := os.Stdout.Write(data)
+	return uint32(n), err
+}
+
+// sysExit implements exit(code a0): it records the exit code and sets
+// State to TERMINATE. Cycle itself does not check State, so the pipeline
+// stops only once the caller's run loop does; another Cycle call still
+// advances it.
+func sysExit(sys *RVI32System, a0, a1, a2, a3, a4, a5, a6 uint32) (uint32, error) {
+	sys.ExitCode = int32(a0)
+	sys.State = TERMINATE
+	return a0, nil
+}
+
+// sysRead implements read(fd a0, buf a1, count a2): the default semihosting
+// set has no input source, so it always reports EOF (zero bytes read).
+func sysRead(sys *RVI32System, a0, a1, a2, a3, a4, a5, a6 uint32) (uint32, error) {
+	return 0, nil
+}
+
+// sysBrk implements brk(addr a0): this simulator never actually moves a
+// heap boundary (RAM is one fixed-size array a program can address
+// directly), so it just echoes addr back, the same response a real brk
+// gives when asked to set the break to its current value.
+func sysBrk(sys *RVI32System, a0, a1, a2, a3, a4, a5, a6 uint32) (uint32, error) {
+	return a0, nil
+}
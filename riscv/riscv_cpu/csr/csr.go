@@ -0,0 +1,205 @@
+// Package csr models the RISC-V machine-mode control and status registers
+// needed to take traps: mstatus, mtvec, mepc, mcause, mtval, mie, mip,
+// mscratch, the read-only ID registers, and the cycle/instret counters.
+package csr
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+const (
+	MSTATUS   = 0x300
+	MISA      = 0x301
+	MIE       = 0x304
+	MTVEC     = 0x305
+	MSCRATCH  = 0x340
+	MEPC      = 0x341
+	MCAUSE    = 0x342
+	MTVAL     = 0x343
+	MIP       = 0x344
+	MCYCLE    = 0xB00
+	MINSTRET  = 0xB02
+	MVENDORID = 0xF11
+	MARCHID   = 0xF12
+	MIMPID    = 0xF13
+	MHARTID   = 0xF14
+)
+
+// Trap causes (mcause), synchronous exceptions only set the interrupt bit
+// to 0; bit 31 marks an interrupt in mcause's MSB-is-set convention.
+const (
+	CauseInstructionAddressMisaligned = 0x0
+	CauseIllegalInstruction           = 0x2
+	CauseBreakpoint                   = 0x3
+	CauseLoadAddressMisaligned        = 0x4
+	CauseLoadAccessFault              = 0x5
+	CauseStoreAddressMisaligned       = 0x6
+	CauseStoreAccessFault             = 0x7
+	CauseECallFromMMode               = 0xB
+
+	CauseInterruptBit          = uint32(1) << 31
+	CauseMachineTimerInterrupt = CauseInterruptBit | 0x7
+)
+
+// mie/mip bit positions for the one interrupt source this core models.
+const (
+	MieMTIE = uint32(1) << 7
+	MipMTIP = uint32(1) << 7
+)
+
+// mstatus bit positions relevant to a single machine-mode-only core.
+const (
+	MstatusMIE      = uint32(1) << 3
+	MstatusMPIE     = uint32(1) << 7
+	MstatusMPPShift = 11
+	MstatusMPPMask  = uint32(0x3) << MstatusMPPShift
+)
+
+// CSRFile holds the subset of machine-mode CSRs this simulator implements.
+// It has no pipeline latch discipline of its own: CSR writes take effect
+// immediately in Execute, matching how the register file is read/written
+// within the same Compute/LatchNext cycle elsewhere in this package.
+type CSRFile struct {
+	Mstatus  uint32
+	Mtvec    uint32
+	Mepc     uint32
+	Mcause   uint32
+	Mtval    uint32
+	Mie      uint32
+	Mip      uint32
+	Mscratch uint32
+
+	// Mtime/Mtimecmp mirror the timer device's live registers, so anything
+	// reading CSR state (snapshots, a debugger) sees them without reaching
+	// into system_interface.Timer_Device directly. The CPU keeps these in
+	// sync via SyncTimer; they are not themselves CSR-addressable, the
+	// same way real mtime/mtimecmp live in the CLINT's MMIO space rather
+	// than the CSR space.
+	Mtime    uint64
+	Mtimecmp uint64
+
+	Mcycle   uint64
+	Minstret uint64
+}
+
+// NewCSRFile returns a CSR file with misa/mvendorid/marchid/mimpid/mhartid
+// wired as read-only constants baked into Read rather than stored fields.
+func NewCSRFile() *CSRFile {
+	return &CSRFile{}
+}
+
+// Read returns the current value of the CSR at addr, and whether addr is a
+// CSR this core implements.
+func (f *CSRFile) Read(addr uint32) (uint32, bool) {
+	switch addr {
+	case MSTATUS:
+		return f.Mstatus, true
+	case MISA:
+		return 0x40001101, true // RV32IMA, machine mode only; C stays clear since only part of RVC is decompressed
+	case MIE:
+		return f.Mie, true
+	case MTVEC:
+		return f.Mtvec, true
+	case MSCRATCH:
+		return f.Mscratch, true
+	case MEPC:
+		return f.Mepc, true
+	case MCAUSE:
+		return f.Mcause, true
+	case MTVAL:
+		return f.Mtval, true
+	case MIP:
+		return f.Mip, true
+	case MCYCLE:
+		return uint32(f.Mcycle), true
+	case MINSTRET:
+		return uint32(f.Minstret), true
+	case MVENDORID, MARCHID, MIMPID, MHARTID:
+		return 0, true
+	}
+	return 0, false
+}
+
+// Write stores value into the CSR at addr, and reports whether addr is a
+// CSR this core implements. Read-only CSRs (misa, the ID registers) are
+// accepted but silently ignored, matching how real cores treat CSRRW to a
+// read-only register with a matching write value.
+func (f *CSRFile) Write(addr uint32, value uint32) bool {
+	switch addr {
+	case MSTATUS:
+		f.Mstatus = value
+	case MIE:
+		f.Mie = value
+	case MTVEC:
+		f.Mtvec = value
+	case MSCRATCH:
+		f.Mscratch = value
+	case MEPC:
+		f.Mepc = value & ^uint32(0x1) // IALIGN=16 (RVC): bit 0 is always zero
+	case MCAUSE:
+		f.Mcause = value
+	case MTVAL:
+		f.Mtval = value
+	case MIP:
+		f.Mip = value
+	case MISA, MVENDORID, MARCHID, MIMPID, MHARTID:
+		// read-only
+	default:
+		return false
+	}
+	return true
+}
+
+// EnterTrap records the faulting PC/cause/tval, clears MIE after saving it
+// to MPIE, and returns the PC the fetch stage should redirect to (mtvec,
+// vectored for interrupts when mtvec.MODE==1).
+func (f *CSRFile) EnterTrap(pc uint32, cause uint32, tval uint32) uint32 {
+	f.Mepc = pc
+	f.Mcause = cause
+	f.Mtval = tval
+
+	if f.Mstatus&MstatusMIE != 0 {
+		f.Mstatus |= MstatusMPIE
+	} else {
+		f.Mstatus &^= MstatusMPIE
+	}
+	f.Mstatus &^= MstatusMIE
+	f.Mstatus = (f.Mstatus &^ MstatusMPPMask) | (0x3 << MstatusMPPShift) // MPP=M
+
+	base := f.Mtvec &^ 0x3
+	mode := f.Mtvec & 0x3
+	if mode == 1 && cause&CauseInterruptBit != 0 {
+		return base + 4*(cause&^CauseInterruptBit)
+	}
+	return base
+}
+
+// MRET restores mstatus.MIE from MPIE and returns mepc, the PC execution
+// resumes at.
+func (f *CSRFile) MRET() uint32 {
+	if f.Mstatus&MstatusMPIE != 0 {
+		f.Mstatus |= MstatusMIE
+	} else {
+		f.Mstatus &^= MstatusMIE
+	}
+	f.Mstatus |= MstatusMPIE
+	return f.Mepc
+}
+
+// SyncTimer refreshes the Mtime/Mtimecmp mirror, meant to be called once
+// per cycle with the timer device's current registers.
+func (f *CSRFile) SyncTimer(mtime uint64, mtimecmp uint64) {
+	f.Mtime = mtime
+	f.Mtimecmp = mtimecmp
+}
+
+// Serialize writes every CSR as part of a full machine snapshot.
+func (f *CSRFile) Serialize(w io.Writer) error {
+	return binary.Write(w, binary.LittleEndian, f)
+}
+
+// Deserialize restores a CSR file written by Serialize.
+func (f *CSRFile) Deserialize(r io.Reader) error {
+	return binary.Read(r, binary.LittleEndian, f)
+}
@@ -0,0 +1,343 @@
+package riscv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	. "riscv/system_interface"
+)
+
+// GDBStub is a minimal GDB Remote Serial Protocol server for RVI32System:
+// enough of g/G (register file), m/M (memory), s/c (step/continue), and
+// Z0/z0 (software breakpoints) / Z2/z2 (write watchpoints) for
+// `gdb-multiarch --target=riscv:rv32` to attach over TCP, single-step a
+// running program, and inspect its register file and RAM.
+type GDBStub struct {
+	sys *RVI32System
+
+	stopped bool
+	stopPC  uint32
+}
+
+// NewGDBStub wraps sys for debugging and registers itself as sys's
+// Debugger, so a breakpoint set via a Z0 packet reaches OnBreak. Callers
+// still need to call ListenAndServe (or Serve on an existing connection)
+// to actually start accepting GDB commands.
+func NewGDBStub(sys *RVI32System) *GDBStub {
+	g := &GDBStub{sys: sys}
+	sys.SetDebugger(g)
+	return g
+}
+
+// OnBreak implements pipeline.Debugger: it just records that a breakpoint
+// fired, for the continue/step loop below to notice and stop driving
+// Cycle() afterward.
+func (g *GDBStub) OnBreak(pc uint32) {
+	g.stopped = true
+	g.stopPC = pc
+}
+
+// ListenAndServe accepts one GDB connection at addr (e.g. "localhost:1234")
+// and serves RSP packets until the connection closes or a kill packet
+// arrives.
+func (g *GDBStub) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+
+	conn, err := ln.Accept()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return g.Serve(conn)
+}
+
+// Serve reads and responds to RSP packets on conn until it closes or a 'k'
+// (kill) packet arrives.
+func (g *GDBStub) Serve(conn net.Conn) error {
+	r := bufio.NewReader(conn)
+	for {
+		packet, err := readPacket(r)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if _, err := conn.Write([]byte("+")); err != nil {
+			return err
+		}
+
+		reply, done := g.dispatch(packet)
+		if reply != "" {
+			if err := writePacket(conn, reply); err != nil {
+				return err
+			}
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+// dispatch handles one RSP command and returns its reply (empty means send
+// nothing back) plus whether the session should end.
+func (g *GDBStub) dispatch(packet string) (reply string, done bool) {
+	if packet == "" {
+		return "", false
+	}
+
+	switch packet[0] {
+	case '?': // reason the target halted
+		return "S05", false
+
+	case 'g': // read the whole register file: x0-x31, then pc
+		var sb strings.Builder
+		for i := 0; i < 32; i++ {
+			sb.WriteString(encodeLE32(g.sys.regFile[i].GetN()))
+		}
+		sb.WriteString(encodeLE32(g.sys.IF.GetFetchValuesOut().Pc()))
+		return sb.String(), false
+
+	case 'G': // write the whole register file: x0-x31, then pc
+		hex := packet[1:]
+		for i := 0; i < 32 && len(hex) >= (i+1)*8; i++ {
+			if i == 0 {
+				continue // x0 is hardwired zero
+			}
+			g.sys.regFile[i].SetN(decodeLE32(hex[i*8 : i*8+8]))
+		}
+		return "OK", false
+
+	case 'm': // m addr,length: read memory
+		addr, length, ok := parseAddrLength(packet[1:])
+		if !ok {
+			return "E01", false
+		}
+		var sb strings.Builder
+		for i := uint32(0); i < length; i++ {
+			v, err := g.busReadByte(addr + i)
+			if err != nil {
+				return "E02", false
+			}
+			fmt.Fprintf(&sb, "%02x", v)
+		}
+		return sb.String(), false
+
+	case 'M': // M addr,length:XX...: write memory
+		addr, data, ok := parseWrite(packet[1:])
+		if !ok {
+			return "E01", false
+		}
+		for i := 0; i+2 <= len(data); i += 2 {
+			v, err := strconv.ParseUint(data[i:i+2], 16, 8)
+			if err != nil {
+				return "E01", false
+			}
+			if err := g.busWriteByte(addr+uint32(i/2), byte(v)); err != nil {
+				return "E02", false
+			}
+		}
+		return "OK", false
+
+	case 's': // single-step one instruction
+		g.step()
+		return "S05", false
+
+	case 'c': // continue until a breakpoint/watchpoint or termination
+		g.cont()
+		if g.sys.State == TERMINATE {
+			return fmt.Sprintf("W%02x", uint8(g.sys.ExitCode)), false
+		}
+		return "S05", false
+
+	case 'Z', 'z':
+		return g.dispatchBreakWatch(packet), false
+
+	case 'k': // kill: end the session
+		return "", true
+
+	default:
+		return "", false // unsupported packet: empty reply per the RSP spec
+	}
+}
+
+// dispatchBreakWatch handles Z/z (insert/remove breakpoint or watchpoint):
+// "Z<type>,<addr>,<kind>". Only type 0 (software breakpoint, backed by
+// InstructionFetchStage's breakpoint map) and type 2 (write watchpoint,
+// backed by SystemInterface's write watchpoints) are implemented; other
+// types get an empty reply, which GDB treats as "unsupported" and falls
+// back to its own software single-step breakpoints.
+func (g *GDBStub) dispatchBreakWatch(packet string) string {
+	insert := packet[0] == 'Z'
+	parts := strings.SplitN(packet[1:], ",", 3)
+	if len(parts) < 2 {
+		return "E01"
+	}
+	addr64, err := strconv.ParseUint(parts[1], 16, 32)
+	if err != nil {
+		return "E01"
+	}
+	addr := uint32(addr64)
+
+	switch parts[0] {
+	case "0":
+		if insert {
+			g.sys.AddBreakpoint(addr)
+		} else {
+			g.sys.RemoveBreakpoint(addr)
+		}
+		return "OK"
+	case "2":
+		if insert {
+			// SystemInterface has no per-watchpoint removal, only
+			// ClearWatchpoints for all of them, so z2 is accepted but
+			// leaves this watchpoint armed.
+			g.sys.bus.AddWriteWatchpoint(addr, 0xFFFFFFFF, func(hitAddr uint32, value uint32, isWrite bool) {
+				g.stopped = true
+				g.stopPC = hitAddr
+			})
+		}
+		return "OK"
+	default:
+		return ""
+	}
+}
+
+// step runs enough Cycle() calls to retire one instruction, the same
+// per-instruction cycle budget this package's own tests use (see
+// trap_test.go's len(program)*6), stopping early on a breakpoint hit or
+// termination.
+func (g *GDBStub) step() {
+	g.stopped = false
+	for i := 0; i < 6 && g.sys.State != TERMINATE && !g.stopped; i++ {
+		g.sys.Cycle()
+	}
+}
+
+// cont drives Cycle() until a breakpoint/watchpoint fires or the program
+// terminates.
+func (g *GDBStub) cont() {
+	g.stopped = false
+	for g.sys.State != TERMINATE && !g.stopped {
+		g.sys.Cycle()
+	}
+}
+
+// busReadByte/busWriteByte poll the bus until a transaction is ready,
+// since GDB's memory commands aren't themselves clocked by Cycle().
+func (g *GDBStub) busReadByte(addr uint32) (byte, error) {
+	for {
+		v, ready, err := g.sys.bus.Read(addr, MEMORY_WIDTH_BYTE)
+		if err != nil {
+			return 0, err
+		}
+		if ready {
+			return byte(v), nil
+		}
+	}
+}
+
+func (g *GDBStub) busWriteByte(addr uint32, value byte) error {
+	for {
+		ready, err := g.sys.bus.Write(addr, uint32(value), MEMORY_WIDTH_BYTE)
+		if err != nil {
+			return err
+		}
+		if ready {
+			return nil
+		}
+	}
+}
+
+// encodeLE32/decodeLE32 convert a register value to/from the RSP 'g'/'G'
+// wire format: each register's raw bytes in target (little-endian) order.
+func encodeLE32(v uint32) string {
+	return fmt.Sprintf("%02x%02x%02x%02x", v&0xFF, (v>>8)&0xFF, (v>>16)&0xFF, (v>>24)&0xFF)
+}
+
+func decodeLE32(hex string) uint32 {
+	var b [4]uint64
+	for i := range b {
+		b[i], _ = strconv.ParseUint(hex[i*2:i*2+2], 16, 8)
+	}
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+}
+
+// parseAddrLength parses "addr,length" (both hex) from an 'm' packet.
+func parseAddrLength(s string) (addr uint32, length uint32, ok bool) {
+	parts := strings.SplitN(s, ",", 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	a, err1 := strconv.ParseUint(parts[0], 16, 32)
+	l, err2 := strconv.ParseUint(parts[1], 16, 32)
+	if err1 != nil || err2 != nil {
+		return 0, 0, false
+	}
+	return uint32(a), uint32(l), true
+}
+
+// parseWrite parses "addr,length:XX..." from an 'M' packet; length itself
+// is redundant with len(data)/2 and isn't separately validated.
+func parseWrite(s string) (addr uint32, data string, ok bool) {
+	comma := strings.IndexByte(s, ',')
+	colon := strings.IndexByte(s, ':')
+	if comma < 0 || colon < 0 || colon < comma {
+		return 0, "", false
+	}
+	a, err := strconv.ParseUint(s[:comma], 16, 32)
+	if err != nil {
+		return 0, "", false
+	}
+	return uint32(a), s[colon+1:], true
+}
+
+// readPacket reads one "$<data>#<checksum>" frame, skipping anything
+// before the leading '$' (stray acks, a Ctrl-C byte) and the trailing
+// checksum, which this best-effort server does not validate.
+func readPacket(r *bufio.Reader) (string, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if b == '$' {
+			break
+		}
+	}
+	var sb strings.Builder
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if b == '#' {
+			break
+		}
+		sb.WriteByte(b)
+	}
+	if _, err := r.Discard(2); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// writePacket frames data as "$<data>#<checksum>", checksum being the
+// 8-bit sum of data's bytes, per the RSP spec.
+func writePacket(w io.Writer, data string) error {
+	sum := 0
+	for i := 0; i < len(data); i++ {
+		sum += int(data[i])
+	}
+	_, err := fmt.Fprintf(w, "$%s#%02x", data, sum&0xFF)
+	return err
+}
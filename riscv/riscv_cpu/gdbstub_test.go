@@ -0,0 +1,140 @@
+package riscv
+
+import (
+	"fmt"
+	. "riscv/pipeline"
+	. "riscv/system_interface"
+	"testing"
+)
+
+// TestGDBStubQueryHaltReason checks '?' (reason the target halted), the
+// first packet a GDB client sends right after attaching.
+func TestGDBStubQueryHaltReason(t *testing.T) {
+	g := NewGDBStub(NewRVI32System())
+
+	reply, done := g.dispatch("?")
+	if reply != "S05" || done {
+		t.Fatalf(`dispatch("?") = (%q, %v); want ("S05", false)`, reply, done)
+	}
+}
+
+// TestGDBStubReadWriteRegisters writes x5 via a 'G' packet and checks a
+// subsequent 'g' packet reports it back in the right slot of the
+// little-endian register dump.
+func TestGDBStubReadWriteRegisters(t *testing.T) {
+	g := NewGDBStub(NewRVI32System())
+
+	var regs [32]uint32
+	regs[5] = 0xDEADBEEF
+
+	var sb []byte
+	for _, v := range regs {
+		sb = append(sb, []byte(encodeLE32(v))...)
+	}
+
+	reply, done := g.dispatch("G" + string(sb))
+	if reply != "OK" || done {
+		t.Fatalf(`dispatch("G...") = (%q, %v); want ("OK", false)`, reply, done)
+	}
+
+	reply, done = g.dispatch("g")
+	if done {
+		t.Fatalf(`dispatch("g") reported done`)
+	}
+	if len(reply) != 33*8 {
+		t.Fatalf("dispatch(\"g\") reply length = %d; want %d", len(reply), 33*8)
+	}
+	got := reply[5*8 : 5*8+8]
+	want := encodeLE32(0xDEADBEEF)
+	if got != want {
+		t.Fatalf("x5 in 'g' reply = %q; want %q", got, want)
+	}
+}
+
+// TestGDBStubMemoryReadWrite writes a few bytes into RAM via an 'M' packet
+// and reads them back via 'm', the pair a GDB client uses to inspect and
+// patch target memory.
+func TestGDBStubMemoryReadWrite(t *testing.T) {
+	g := NewGDBStub(NewRVI32System())
+
+	addr := uint32(MEMORY_MAP_RAM_START)
+	reply, done := g.dispatch(fmt.Sprintf("M%x,4:deadbeef", addr))
+	if reply != "OK" || done {
+		t.Fatalf(`dispatch("M...") = (%q, %v); want ("OK", false)`, reply, done)
+	}
+
+	reply, done = g.dispatch(fmt.Sprintf("m%x,4", addr))
+	if done {
+		t.Fatalf(`dispatch("m...") reported done`)
+	}
+	if reply != "deadbeef" {
+		t.Fatalf(`dispatch("m...") = %q; want "deadbeef"`, reply)
+	}
+}
+
+// TestGDBStubBreakpointStopsContinue arms a software breakpoint with a Z0
+// packet, then runs a 'c' (continue) and checks it actually stops there
+// instead of running to termination.
+func TestGDBStubBreakpointStopsContinue(t *testing.T) {
+	sys := NewRVI32System()
+	g := NewGDBStub(sys)
+
+	bpAddr := MEMORY_MAP_ROM_START + 8 // third instruction below
+	program := []uint32{
+		ADDI(5, 0, 1),
+		ADDI(5, 5, 1),
+		ADDI(5, 5, 1),
+		ADDI(5, 5, 1),
+	}
+	sys.rom.Load(program)
+
+	reply, done := g.dispatch(fmt.Sprintf("Z0,%x,1", bpAddr))
+	if reply != "OK" || done {
+		t.Fatalf(`dispatch("Z0,...") = (%q, %v); want ("OK", false)`, reply, done)
+	}
+
+	reply, done = g.dispatch("c")
+	if reply != "S05" || done {
+		t.Fatalf(`dispatch("c") = (%q, %v); want ("S05", false)`, reply, done)
+	}
+	if sys.State == TERMINATE {
+		t.Fatalf("continue ran to termination instead of stopping at the breakpoint")
+	}
+	if g.stopPC != uint32(bpAddr) {
+		t.Fatalf("stopped at pc 0x%X; want 0x%X", g.stopPC, bpAddr)
+	}
+
+	reply, done = g.dispatch(fmt.Sprintf("z0,%x,1", bpAddr))
+	if reply != "OK" || done {
+		t.Fatalf(`dispatch("z0,...") = (%q, %v); want ("OK", false)`, reply, done)
+	}
+}
+
+// TestGDBStubStep checks 's' (single-step) replies S05 without needing a
+// breakpoint armed.
+func TestGDBStubStep(t *testing.T) {
+	sys := NewRVI32System()
+	g := NewGDBStub(sys)
+
+	program := []uint32{
+		ADDI(5, 0, 1),
+		ADDI(5, 5, 1),
+	}
+	sys.rom.Load(program)
+
+	reply, done := g.dispatch("s")
+	if reply != "S05" || done {
+		t.Fatalf(`dispatch("s") = (%q, %v); want ("S05", false)`, reply, done)
+	}
+}
+
+// TestGDBStubKillEndsSession checks 'k' reports the session should end,
+// with no reply to send first.
+func TestGDBStubKillEndsSession(t *testing.T) {
+	g := NewGDBStub(NewRVI32System())
+
+	reply, done := g.dispatch("k")
+	if reply != "" || !done {
+		t.Fatalf(`dispatch("k") = (%q, %v); want ("", true)`, reply, done)
+	}
+}
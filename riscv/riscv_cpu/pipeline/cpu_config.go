@@ -0,0 +1,14 @@
+package pipeline
+
+// CPUConfig holds simulator-wide feature toggles that don't belong to any
+// one pipeline stage, the same way CycleCost holds the cost knobs
+// ExecuteStage and MemoryAccessStage share.
+type CPUConfig struct {
+	// EnableFusion gates FuserStage's peephole pattern matching. When
+	// false, FuserStage is a transparent passthrough and ExecuteStage sees
+	// exactly what DecodeStage produced, with no extra pipeline latency.
+	EnableFusion bool
+}
+
+// DefaultCPUConfig leaves every feature off, matching today's behavior.
+var DefaultCPUConfig = CPUConfig{}
@@ -0,0 +1,24 @@
+package pipeline
+
+// CycleCost models the extra cycles an instruction burns in EXECUTE or
+// MEMORY_ACCESS beyond the one cycle every pipeline stage already spends
+// per RVI32System.Cycle() call, keyed by the same opcode/funct3 switches
+// ExecuteStage and MemoryAccessStage already use to decode the operation.
+// This mirrors the explicit per-instruction cycle-cost tables used by
+// cycle-accurate emulators such as rustboyadvance-ng's ARM7TDMI core.
+type CycleCost struct {
+	BranchTakenBubble uint64 // extra cycles when a branch/jump redirects fetch
+	LoadExtra         uint64 // extra cycles a load charges beyond a plain ALU op
+	StoreExtra        uint64 // extra cycles a store charges beyond a plain ALU op
+	ShiftPerShamt     bool   // SLL/SRL/SLLI/SRLI charge one extra cycle per shift amount
+}
+
+// DefaultCycleCost assumes a single-cycle bus: branches still cost a
+// one-cycle bubble for the redirected fetch, loads/stores don't cost more
+// than a plain ALU op, and shifts aren't scaled by their shift amount.
+var DefaultCycleCost = CycleCost{
+	BranchTakenBubble: 1,
+	LoadExtra:         0,
+	StoreExtra:        0,
+	ShiftPerShamt:     false,
+}
@@ -0,0 +1,214 @@
+package pipeline
+
+// FuserParams bundles what FuserStage needs from the rest of the machine:
+// the feature config it's gated behind, when to run, and where its decoded
+// input comes from, mirroring every other stage's Params struct.
+type FuserParams struct {
+	config *CPUConfig
+
+	shouldStall        func() bool
+	getDecodedValuesIn func() DecodedValues
+}
+
+func NewFuserParams(config *CPUConfig, shouldStall func() bool, getDecodedValuesIn func() DecodedValues) *FuserParams {
+	return &FuserParams{
+		config:             config,
+		shouldStall:        shouldStall,
+		getDecodedValuesIn: getDecodedValuesIn,
+	}
+}
+
+// FuserStage sits between DecodeStage and ExecuteStage and runs a
+// peephole/macro-op fusion pass: a one-instruction sliding window that
+// recognizes a handful of two-instruction idioms (LUI+ADDI building a
+// 32-bit constant, AUIPC+ADDI/LW building a PC-relative address or load,
+// an SLLI/SRLI pair zero-extending a field, ADD+LW indexing through a
+// just-computed address) and collapses each into a single synthesized op,
+// the way an out-of-order front end fuses macro-ops before issue.
+//
+// Fusing across two instructions means holding the first back for one
+// extra cycle while its successor is decoded: RVI32System.Cycle does not
+// run EXECUTE on a cycle where buffered is valid but unresolved (see
+// ReadyValid). When EnableFusion is false,
+// Compute passes the incoming instruction straight through every cycle,
+// so existing cycle counts are unaffected.
+type FuserStage struct {
+	config *CPUConfig
+
+	shouldStall        func() bool
+	getDecodedValuesIn func() DecodedValues
+
+	// buffered holds the previous cycle's decoded instruction while Fuser
+	// waits to see whether its successor completes a fusable pair. Plain
+	// fields, not RBool/RByte latches: they track progress across several
+	// Compute calls the same way InstructionFetchStage's fetchIssued does.
+	buffered      DecodedValues
+	bufferedValid bool
+
+	ready      DecodedValues
+	readyValid bool
+
+	fusionCount uint64
+}
+
+func NewFuserStage(params *FuserParams) *FuserStage {
+	fs := &FuserStage{}
+	fs.config = params.config
+	fs.shouldStall = params.shouldStall
+	fs.getDecodedValuesIn = params.getDecodedValuesIn
+	return fs
+}
+
+// ReadyValid reports whether Compute produced something for ExecuteStage
+// this pass. RVI32System.Cycle skips EXECUTE for any cycle where this is
+// false, giving the buffered instruction a chance to pair with its
+// successor before anything executes.
+func (fs *FuserStage) ReadyValid() bool {
+	return fs.readyValid
+}
+
+// FusionCount is the cycle-accounting hook: how many instruction pairs
+// Compute has actually collapsed into a fused op, so a test can assert
+// fusion fired instead of just trusting the feature flag.
+func (fs *FuserStage) FusionCount() uint64 {
+	return fs.fusionCount
+}
+
+// FusionCyclesSaved estimates the cycles fusion has avoided: each fused
+// pair skips the extra DECODE/FUSE/EXECUTE/MEMORY_ACCESS/WRITE_BACK trip
+// a second, un-fused instruction would otherwise cost.
+func (fs *FuserStage) FusionCyclesSaved() uint64 {
+	return fs.fusionCount * 5
+}
+
+func (fs *FuserStage) Compute() {
+	if fs.shouldStall() {
+		return
+	}
+
+	current := fs.getDecodedValuesIn()
+
+	if !fs.config.EnableFusion {
+		fs.ready = current
+		fs.readyValid = true
+		fs.bufferedValid = false
+		return
+	}
+
+	if !fs.bufferedValid {
+		if !isFusionCandidate(current) {
+			fs.ready = current
+			fs.readyValid = true
+			return
+		}
+		fs.buffered = current
+		fs.bufferedValid = true
+		fs.readyValid = false
+		return
+	}
+
+	if fused, tag, ok := tryFuse(fs.buffered, current); ok {
+		fused.isFusedOp = true
+		fused.fusedTag = tag
+		fs.ready = fused
+		fs.readyValid = true
+		fs.bufferedValid = false
+		fs.fusionCount++
+		return
+	}
+
+	// No match: release the buffered first instruction unmodified, and let
+	// current take its place in the buffer. If current can start a pairing
+	// it waits there for its successor; if not, tryFuse cannot match it on
+	// the next Compute, so it is released unmodified then, one cycle later.
+	fs.ready = fs.buffered
+	fs.readyValid = true
+	fs.buffered = current
+	fs.bufferedValid = true
+}
+
+// LatchNext commits nothing of its own: ready is a plain snapshot already
+// settled by the time ExecuteStage reads it next cycle, the same way
+// FetchValues/DecodedValues themselves need no latch step.
+func (fs *FuserStage) LatchNext() {
+}
+
+// GetFusedValuesOut returns either the original decoded instruction or a
+// synthesized fused op, for ExecuteStage to consume in place of
+// DecodeStage's direct output.
+func (fs *FuserStage) GetFusedValuesOut() DecodedValues {
+	return fs.ready
+}
+
+// isFusionCandidate reports whether decoded could be the first half of one
+// of the patterns tryFuse recognizes, so an instruction that can never
+// start a fusable pair executes immediately instead of paying a cycle of
+// buffering for nothing.
+func isFusionCandidate(first DecodedValues) bool {
+	if first.isLuiOp || first.isAuipcOp {
+		return true
+	}
+	if first.opcode == IMMEDIATE_OPCODE && first.func3 == OP_SLL {
+		return true
+	}
+	if first.opcode == REGISTER_OPCODE && first.func3 == OP_ADD_SUB && first.func7 == 0 {
+		return true
+	}
+	return false
+}
+
+// tryFuse matches the two-instruction idioms documented on FuserStage,
+// returning the synthesized DecodedValues and SemanticTag for the ones
+// that apply, or ok=false to mean "execute first unmodified."
+func tryFuse(first, second DecodedValues) (DecodedValues, SemanticTag, bool) {
+	switch {
+	case first.isLuiOp &&
+		second.opcode == IMMEDIATE_OPCODE && second.func3 == OP_ADD_SUB &&
+		second.rs1Addr == first.rd && second.rd == first.rd:
+		// LUI rd,hi ; ADDI rd,rd,lo -> LOAD_IMM32 rd,imm32
+		out := first
+		out.imm32 = first.imm32 + second.imm32
+		out.rd = second.rd
+		return out, TagLoadImm32, true
+
+	case first.isAuipcOp &&
+		second.opcode == IMMEDIATE_OPCODE && second.func3 == OP_ADD_SUB &&
+		second.rs1Addr == first.rd && second.rd == first.rd:
+		// AUIPC rd,hi ; ADDI rd,rd,lo -> LOAD_PC_REL rd,offset
+		out := first
+		out.imm32 = first.imm32 + second.imm32
+		out.rd = second.rd
+		return out, TagLoadPcRel, true
+
+	case first.isAuipcOp &&
+		second.isLoadOp && second.func3 == LOAD_FUNC3_LW &&
+		second.rs1Addr == first.rd:
+		// AUIPC rd,hi ; LW rd,lo(rd) -> LOAD_PC_REL_MEM rd,offset
+		out := first
+		out.imm32 = first.imm32 + second.imm32
+		out.rd = second.rd
+		out.isLoadOp = true
+		return out, TagLoadPcRelMem, true
+
+	case first.opcode == IMMEDIATE_OPCODE && first.func3 == OP_SLL &&
+		second.opcode == IMMEDIATE_OPCODE && second.func3 == OP_SRL && second.func7 == 0 &&
+		second.rs1Addr == first.rd && second.rd == first.rd &&
+		first.shamt == second.shamt:
+		// SLLI rd,rs,32-k ; SRLI rd,rd,32-k -> ZEXT.k rd,rs
+		out := first
+		out.imm32 = int32(32 - first.shamt)
+		out.rd = second.rd
+		return out, TagZext, true
+
+	case first.opcode == REGISTER_OPCODE && first.func3 == OP_ADD_SUB && first.func7 == 0 &&
+		second.isLoadOp && second.func3 == LOAD_FUNC3_LW &&
+		second.rs1Addr == first.rd && second.imm32 == 0:
+		// ADD rd,rs1,rs2 ; LW rd,0(rd) -> INDEXED_LOAD rd,[rs1+rs2]
+		out := first
+		out.rd = second.rd
+		out.isLoadOp = true
+		return out, TagIndexedLoad, true
+	}
+
+	return DecodedValues{}, 0, false
+}
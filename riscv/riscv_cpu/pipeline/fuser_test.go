@@ -0,0 +1,148 @@
+package pipeline
+
+import "testing"
+
+// feedFuser runs FuserStage across as many Compute calls as it takes to
+// accept both first and second and produce a ready result, the way
+// RVI32System.Cycle calls it once per cycle and runs EXECUTE only once
+// ReadyValid is true.
+func feedFuser(t *testing.T, fs *FuserStage) DecodedValues {
+	t.Helper()
+
+	for i := 0; i < 4; i++ {
+		fs.Compute()
+		if fs.ReadyValid() {
+			if i == 0 {
+				t.Fatalf("FuserStage reported ready before seeing a second instruction")
+			}
+			return fs.GetFusedValuesOut()
+		}
+	}
+	t.Fatalf("FuserStage never became ready")
+	return DecodedValues{}
+}
+
+func newTestFuser(t *testing.T, decoded ...DecodedValues) *FuserStage {
+	t.Helper()
+	i := 0
+	config := &CPUConfig{EnableFusion: true}
+	fs := NewFuserStage(NewFuserParams(config, func() bool { return false }, func() DecodedValues {
+		d := decoded[i]
+		if i < len(decoded)-1 {
+			i++
+		}
+		return d
+	}))
+	return fs
+}
+
+func TestFuserLoadImm32(t *testing.T) {
+	first := DecodedValues{isLuiOp: true, rd: 5, imm32: 0x12345000}
+	second := DecodedValues{opcode: IMMEDIATE_OPCODE, func3: OP_ADD_SUB, rs1Addr: 5, rd: 5, imm32: 0x678}
+
+	fs := newTestFuser(t, first, second)
+	got := feedFuser(t, fs)
+
+	if !got.isFusedOp || got.fusedTag != TagLoadImm32 {
+		t.Fatalf("expected a fused LOAD_IMM32, got %+v", got)
+	}
+	if got.imm32 != 0x12345000+0x678 || got.rd != 5 {
+		t.Fatalf("fused LOAD_IMM32 mismatch: %+v", got)
+	}
+	if fs.FusionCount() != 1 {
+		t.Fatalf("FusionCount = %d, want 1", fs.FusionCount())
+	}
+}
+
+func TestFuserLoadPcRel(t *testing.T) {
+	first := DecodedValues{isAuipcOp: true, rd: 6, imm32: 0x1000}
+	second := DecodedValues{opcode: IMMEDIATE_OPCODE, func3: OP_ADD_SUB, rs1Addr: 6, rd: 6, imm32: 4}
+
+	fs := newTestFuser(t, first, second)
+	got := feedFuser(t, fs)
+
+	if !got.isFusedOp || got.fusedTag != TagLoadPcRel {
+		t.Fatalf("expected a fused LOAD_PC_REL, got %+v", got)
+	}
+	if got.imm32 != 0x1004 || got.rd != 6 {
+		t.Fatalf("fused LOAD_PC_REL mismatch: %+v", got)
+	}
+	if fs.FusionCount() != 1 {
+		t.Fatalf("FusionCount = %d, want 1", fs.FusionCount())
+	}
+}
+
+func TestFuserLoadPcRelMem(t *testing.T) {
+	first := DecodedValues{isAuipcOp: true, rd: 7, imm32: 0x2000}
+	second := DecodedValues{isLoadOp: true, func3: LOAD_FUNC3_LW, rs1Addr: 7, rd: 8, imm32: 16}
+
+	fs := newTestFuser(t, first, second)
+	got := feedFuser(t, fs)
+
+	if !got.isFusedOp || got.fusedTag != TagLoadPcRelMem {
+		t.Fatalf("expected a fused LOAD_PC_REL_MEM, got %+v", got)
+	}
+	if !got.isLoadOp || got.imm32 != 0x2010 || got.rd != 8 {
+		t.Fatalf("fused LOAD_PC_REL_MEM mismatch: %+v", got)
+	}
+	if fs.FusionCount() != 1 {
+		t.Fatalf("FusionCount = %d, want 1", fs.FusionCount())
+	}
+}
+
+func TestFuserZext(t *testing.T) {
+	first := DecodedValues{opcode: IMMEDIATE_OPCODE, func3: OP_SLL, rd: 9, shamt: 24}
+	second := DecodedValues{opcode: IMMEDIATE_OPCODE, func3: OP_SRL, func7: 0, rs1Addr: 9, rd: 9, shamt: 24}
+
+	fs := newTestFuser(t, first, second)
+	got := feedFuser(t, fs)
+
+	if !got.isFusedOp || got.fusedTag != TagZext {
+		t.Fatalf("expected a fused ZEXT, got %+v", got)
+	}
+	if got.imm32 != 8 || got.rd != 9 {
+		t.Fatalf("fused ZEXT mismatch: %+v", got)
+	}
+	if fs.FusionCount() != 1 {
+		t.Fatalf("FusionCount = %d, want 1", fs.FusionCount())
+	}
+}
+
+func TestFuserIndexedLoad(t *testing.T) {
+	first := DecodedValues{opcode: REGISTER_OPCODE, func3: OP_ADD_SUB, func7: 0, rd: 10, rs1Addr: 1, rs2Addr: 2}
+	second := DecodedValues{isLoadOp: true, func3: LOAD_FUNC3_LW, rs1Addr: 10, rd: 11, imm32: 0}
+
+	fs := newTestFuser(t, first, second)
+	got := feedFuser(t, fs)
+
+	if !got.isFusedOp || got.fusedTag != TagIndexedLoad {
+		t.Fatalf("expected a fused INDEXED_LOAD, got %+v", got)
+	}
+	if !got.isLoadOp || got.rd != 11 {
+		t.Fatalf("fused INDEXED_LOAD mismatch: %+v", got)
+	}
+	if fs.FusionCount() != 1 {
+		t.Fatalf("FusionCount = %d, want 1", fs.FusionCount())
+	}
+}
+
+func TestFuserNonCandidatePassesThrough(t *testing.T) {
+	// OR can't start any of the five patterns tryFuse recognizes (only LUI,
+	// AUIPC, SLLI, and ADD can), so it must execute the same cycle it
+	// arrives instead of paying a cycle of buffering for nothing.
+	only := DecodedValues{opcode: REGISTER_OPCODE, func3: OP_OR, func7: 0, rd: 1, rs1Addr: 2, rs2Addr: 3}
+	config := &CPUConfig{EnableFusion: true}
+	fs := NewFuserStage(NewFuserParams(config, func() bool { return false }, func() DecodedValues { return only }))
+
+	fs.Compute()
+	if !fs.ReadyValid() {
+		t.Fatalf("a non-candidate instruction should be ready the same cycle, not buffered")
+	}
+	got := fs.GetFusedValuesOut()
+	if got.isFusedOp {
+		t.Fatalf("non-candidate instruction should not be marked fused: %+v", got)
+	}
+	if fs.FusionCount() != 0 {
+		t.Fatalf("FusionCount = %d, want 0", fs.FusionCount())
+	}
+}
@@ -0,0 +1,269 @@
+package pipeline
+
+// Format is the encoding shape an instruction uses, mirroring LLVM
+// TableGen's per-instruction Format class (R/I/S/B/U/J from the RV32I base
+// spec; extensions add new formats by adding new consts here).
+type Format byte
+
+const (
+	FormatR Format = iota
+	FormatI
+	FormatS
+	FormatU
+	FormatJ
+	FormatB
+)
+
+// ImmKind names how an instruction's immediate is packed and sign-extended,
+// so a future table-driven Encode/Decode can share one extraction routine
+// per kind instead of one per mnemonic.
+type ImmKind byte
+
+const (
+	ImmNone  ImmKind = iota
+	ImmI             // sign-extended bits [31:20]
+	ImmS             // sign-extended {bits[31:25], bits[11:7]}
+	ImmU             // bits [31:12] << 12
+	ImmJ             // sign-extended J-type encoding (see JTypeDecode)
+	ImmB             // sign-extended B-type encoding: {bit31,bit7,bits30:25,bits11:8,0}
+	ImmShamt         // unsigned bits [24:20], shift amount
+	ImmCSR           // unsigned bits [31:20], CSR address
+)
+
+// SemanticTag is what ExecuteStage actually switches on to perform an
+// instruction, decoupled from the bit pattern that produced it. Extensions
+// add new tags and a registration instead of touching existing dispatch
+// code for unrelated instructions.
+type SemanticTag byte
+
+const (
+	TagAluAdd SemanticTag = iota
+	TagAluSub
+	TagAluSll
+	TagAluSlt
+	TagAluSltu
+	TagAluXor
+	TagAluSrl
+	TagAluSra
+	TagAluOr
+	TagAluAnd
+
+	TagMul
+	TagMulh
+	TagMulhsu
+	TagMulhu
+	TagDiv
+	TagDivu
+	TagRem
+	TagRemu
+
+	TagLoadB
+	TagLoadH
+	TagLoadW
+	TagLoadBU
+	TagLoadHU
+
+	TagStoreB
+	TagStoreH
+	TagStoreW
+
+	TagLui
+	TagAuipc
+	TagJal
+	TagJalr
+
+	TagBranchEq
+	TagBranchNe
+	TagBranchLt
+	TagBranchGe
+	TagBranchLtu
+	TagBranchGeu
+
+	TagEcall
+	TagEbreak
+	TagMret
+	TagCsrrw
+	TagCsrrs
+	TagCsrrc
+	TagCsrrwi
+	TagCsrrsi
+	TagCsrrci
+
+	TagLR
+	TagSC
+	TagAmoSwap
+	TagAmoAdd
+	TagAmoXor
+	TagAmoAnd
+	TagAmoOr
+	TagAmoMin
+	TagAmoMax
+	TagAmoMinu
+	TagAmoMaxu
+
+	TagFence
+	TagFenceI
+
+	// Fused (macro-op) pseudo-instructions synthesized by FuserStage. They
+	// never appear in an encoded instruction stream and have no InstrDesc
+	// registration; ExecuteStage only sees one of these when DecodedValues
+	// came back through Fuser with isFusedOp set.
+	TagLoadImm32
+	TagLoadPcRel
+	TagLoadPcRelMem
+	TagZext
+	TagIndexedLoad
+)
+
+// InstrDesc is one TableGen-style record: everything needed to both encode
+// a mnemonic and recognize it while decoding, plus the SemanticTag that
+// tells ExecuteStage what to actually do with it.
+//
+// HasFunc3/HasFunc7/HasSystemImm mark which of those fields participate in
+// recognizing this instruction, since not every format carries all three
+// (U/J instructions have no funct3/funct7, and ECALL/EBREAK/MRET share
+// opcode SYSTEM_OPCODE and funct3 0 but are told apart by the imm12 field).
+type InstrDesc struct {
+	Name   string
+	Format Format
+	Imm    ImmKind
+	Tag    SemanticTag
+
+	Opcode byte
+
+	Func3    byte
+	HasFunc3 bool
+
+	Func7     byte
+	HasFunc7  bool
+	Func7Mask byte // 0 means "exact match" (all 7 bits); set to mask off aq/rl for AMO funct5 matching
+
+	SystemImm    int32
+	HasSystemImm bool
+}
+
+var descsByName = map[string]*InstrDesc{}
+var descsByOpcode = map[byte][]*InstrDesc{}
+
+// register adds a table entry under both indices a caller would need it
+// by: mnemonic for an assembler, and opcode bucket for the decoder.
+func register(d *InstrDesc) *InstrDesc {
+	descsByName[d.Name] = d
+	descsByOpcode[d.Opcode] = append(descsByOpcode[d.Opcode], d)
+	return d
+}
+
+func init() {
+	register(&InstrDesc{Name: "ADD", Format: FormatR, Opcode: REGISTER_OPCODE, Func3: OP_ADD_SUB, HasFunc3: true, Func7: 0b0000000, HasFunc7: true, Tag: TagAluAdd})
+	register(&InstrDesc{Name: "SUB", Format: FormatR, Opcode: REGISTER_OPCODE, Func3: OP_ADD_SUB, HasFunc3: true, Func7: 0b0100000, HasFunc7: true, Tag: TagAluSub})
+	register(&InstrDesc{Name: "SLL", Format: FormatR, Opcode: REGISTER_OPCODE, Func3: OP_SLL, HasFunc3: true, Func7: 0b0000000, HasFunc7: true, Tag: TagAluSll})
+	register(&InstrDesc{Name: "SLT", Format: FormatR, Opcode: REGISTER_OPCODE, Func3: OP_SLT, HasFunc3: true, Func7: 0b0000000, HasFunc7: true, Tag: TagAluSlt})
+	register(&InstrDesc{Name: "SLTU", Format: FormatR, Opcode: REGISTER_OPCODE, Func3: OP_SLTU, HasFunc3: true, Func7: 0b0000000, HasFunc7: true, Tag: TagAluSltu})
+	register(&InstrDesc{Name: "XOR", Format: FormatR, Opcode: REGISTER_OPCODE, Func3: OP_XOR, HasFunc3: true, Func7: 0b0000000, HasFunc7: true, Tag: TagAluXor})
+	register(&InstrDesc{Name: "SRL", Format: FormatR, Opcode: REGISTER_OPCODE, Func3: OP_SRL, HasFunc3: true, Func7: 0b0000000, HasFunc7: true, Tag: TagAluSrl})
+	register(&InstrDesc{Name: "OR", Format: FormatR, Opcode: REGISTER_OPCODE, Func3: OP_OR, HasFunc3: true, Func7: 0b0000000, HasFunc7: true, Tag: TagAluOr})
+	register(&InstrDesc{Name: "AND", Format: FormatR, Opcode: REGISTER_OPCODE, Func3: OP_AND, HasFunc3: true, Func7: 0b0000000, HasFunc7: true, Tag: TagAluAnd})
+
+	register(&InstrDesc{Name: "MUL", Format: FormatR, Opcode: REGISTER_OPCODE, Func3: FUNC3_MUL, HasFunc3: true, Func7: FUNC7_MULDIV, HasFunc7: true, Tag: TagMul})
+	register(&InstrDesc{Name: "MULH", Format: FormatR, Opcode: REGISTER_OPCODE, Func3: FUNC3_MULH, HasFunc3: true, Func7: FUNC7_MULDIV, HasFunc7: true, Tag: TagMulh})
+	register(&InstrDesc{Name: "MULHSU", Format: FormatR, Opcode: REGISTER_OPCODE, Func3: FUNC3_MULHSU, HasFunc3: true, Func7: FUNC7_MULDIV, HasFunc7: true, Tag: TagMulhsu})
+	register(&InstrDesc{Name: "MULHU", Format: FormatR, Opcode: REGISTER_OPCODE, Func3: FUNC3_MULHU, HasFunc3: true, Func7: FUNC7_MULDIV, HasFunc7: true, Tag: TagMulhu})
+	register(&InstrDesc{Name: "DIV", Format: FormatR, Opcode: REGISTER_OPCODE, Func3: FUNC3_DIV, HasFunc3: true, Func7: FUNC7_MULDIV, HasFunc7: true, Tag: TagDiv})
+	register(&InstrDesc{Name: "DIVU", Format: FormatR, Opcode: REGISTER_OPCODE, Func3: FUNC3_DIVU, HasFunc3: true, Func7: FUNC7_MULDIV, HasFunc7: true, Tag: TagDivu})
+	register(&InstrDesc{Name: "REM", Format: FormatR, Opcode: REGISTER_OPCODE, Func3: FUNC3_REM, HasFunc3: true, Func7: FUNC7_MULDIV, HasFunc7: true, Tag: TagRem})
+	register(&InstrDesc{Name: "REMU", Format: FormatR, Opcode: REGISTER_OPCODE, Func3: FUNC3_REMU, HasFunc3: true, Func7: FUNC7_MULDIV, HasFunc7: true, Tag: TagRemu})
+
+	register(&InstrDesc{Name: "ADDI", Format: FormatI, Imm: ImmI, Opcode: IMMEDIATE_OPCODE, Func3: OP_ADD_SUB, HasFunc3: true, Tag: TagAluAdd})
+	register(&InstrDesc{Name: "SLLI", Format: FormatI, Imm: ImmShamt, Opcode: IMMEDIATE_OPCODE, Func3: OP_SLL, HasFunc3: true, Func7: 0b0000000, HasFunc7: true, Tag: TagAluSll})
+	register(&InstrDesc{Name: "SLTI", Format: FormatI, Imm: ImmI, Opcode: IMMEDIATE_OPCODE, Func3: OP_SLT, HasFunc3: true, Tag: TagAluSlt})
+	register(&InstrDesc{Name: "SLTIU", Format: FormatI, Imm: ImmI, Opcode: IMMEDIATE_OPCODE, Func3: OP_SLTU, HasFunc3: true, Tag: TagAluSltu})
+	register(&InstrDesc{Name: "XORI", Format: FormatI, Imm: ImmI, Opcode: IMMEDIATE_OPCODE, Func3: OP_XOR, HasFunc3: true, Tag: TagAluXor})
+	register(&InstrDesc{Name: "SRLI", Format: FormatI, Imm: ImmShamt, Opcode: IMMEDIATE_OPCODE, Func3: OP_SRL, HasFunc3: true, Func7: 0b0000000, HasFunc7: true, Tag: TagAluSrl})
+	register(&InstrDesc{Name: "SRAI", Format: FormatI, Imm: ImmShamt, Opcode: IMMEDIATE_OPCODE, Func3: OP_SRL, HasFunc3: true, Func7: 0b0100000, HasFunc7: true, Tag: TagAluSra})
+	register(&InstrDesc{Name: "ORI", Format: FormatI, Imm: ImmI, Opcode: IMMEDIATE_OPCODE, Func3: OP_OR, HasFunc3: true, Tag: TagAluOr})
+	register(&InstrDesc{Name: "ANDI", Format: FormatI, Imm: ImmI, Opcode: IMMEDIATE_OPCODE, Func3: OP_AND, HasFunc3: true, Tag: TagAluAnd})
+
+	register(&InstrDesc{Name: "LB", Format: FormatI, Imm: ImmI, Opcode: LOAD_OPCODE, Func3: LOAD_FUNC3_LB, HasFunc3: true, Tag: TagLoadB})
+	register(&InstrDesc{Name: "LH", Format: FormatI, Imm: ImmI, Opcode: LOAD_OPCODE, Func3: LOAD_FUNC3_LH, HasFunc3: true, Tag: TagLoadH})
+	register(&InstrDesc{Name: "LW", Format: FormatI, Imm: ImmI, Opcode: LOAD_OPCODE, Func3: LOAD_FUNC3_LW, HasFunc3: true, Tag: TagLoadW})
+	register(&InstrDesc{Name: "LBU", Format: FormatI, Imm: ImmI, Opcode: LOAD_OPCODE, Func3: LOAD_FUNC3_LBU, HasFunc3: true, Tag: TagLoadBU})
+	register(&InstrDesc{Name: "LHU", Format: FormatI, Imm: ImmI, Opcode: LOAD_OPCODE, Func3: LOAD_FUNC3_LHU, HasFunc3: true, Tag: TagLoadHU})
+
+	register(&InstrDesc{Name: "SB", Format: FormatS, Imm: ImmS, Opcode: STORE_OPCODE, Func3: STORE_FUNC3_SB, HasFunc3: true, Tag: TagStoreB})
+	register(&InstrDesc{Name: "SH", Format: FormatS, Imm: ImmS, Opcode: STORE_OPCODE, Func3: STORE_FUNC3_SH, HasFunc3: true, Tag: TagStoreH})
+	register(&InstrDesc{Name: "SW", Format: FormatS, Imm: ImmS, Opcode: STORE_OPCODE, Func3: STORE_FUNC3_SW, HasFunc3: true, Tag: TagStoreW})
+
+	register(&InstrDesc{Name: "LUI", Format: FormatU, Imm: ImmU, Opcode: 0b0110111, Tag: TagLui})
+	register(&InstrDesc{Name: "AUIPC", Format: FormatU, Imm: ImmU, Opcode: 0b0010111, Tag: TagAuipc})
+
+	register(&InstrDesc{Name: "JAL", Format: FormatJ, Imm: ImmJ, Opcode: JAL_OPCODE, Tag: TagJal})
+	register(&InstrDesc{Name: "JALR", Format: FormatI, Imm: ImmI, Opcode: JALR_OPCODE, Func3: 0b000, HasFunc3: true, Tag: TagJalr})
+
+	register(&InstrDesc{Name: "BEQ", Format: FormatB, Imm: ImmB, Opcode: BRANCH_OPCODE, Func3: FUNC3_BEQ, HasFunc3: true, Tag: TagBranchEq})
+	register(&InstrDesc{Name: "BNE", Format: FormatB, Imm: ImmB, Opcode: BRANCH_OPCODE, Func3: FUNC3_BNE, HasFunc3: true, Tag: TagBranchNe})
+	register(&InstrDesc{Name: "BLT", Format: FormatB, Imm: ImmB, Opcode: BRANCH_OPCODE, Func3: FUNC3_BLT, HasFunc3: true, Tag: TagBranchLt})
+	register(&InstrDesc{Name: "BGE", Format: FormatB, Imm: ImmB, Opcode: BRANCH_OPCODE, Func3: FUNC3_BGE, HasFunc3: true, Tag: TagBranchGe})
+	register(&InstrDesc{Name: "BLTU", Format: FormatB, Imm: ImmB, Opcode: BRANCH_OPCODE, Func3: FUNC3_BLTU, HasFunc3: true, Tag: TagBranchLtu})
+	register(&InstrDesc{Name: "BGEU", Format: FormatB, Imm: ImmB, Opcode: BRANCH_OPCODE, Func3: FUNC3_BGEU, HasFunc3: true, Tag: TagBranchGeu})
+
+	register(&InstrDesc{Name: "ECALL", Format: FormatI, Opcode: SYSTEM_OPCODE, Func3: 0b000, HasFunc3: true, SystemImm: SYSTEM_IMM_ECALL, HasSystemImm: true, Tag: TagEcall})
+	register(&InstrDesc{Name: "EBREAK", Format: FormatI, Opcode: SYSTEM_OPCODE, Func3: 0b000, HasFunc3: true, SystemImm: SYSTEM_IMM_EBREAK, HasSystemImm: true, Tag: TagEbreak})
+	register(&InstrDesc{Name: "MRET", Format: FormatI, Opcode: SYSTEM_OPCODE, Func3: 0b000, HasFunc3: true, SystemImm: SYSTEM_IMM_MRET, HasSystemImm: true, Tag: TagMret})
+
+	register(&InstrDesc{Name: "CSRRW", Format: FormatI, Imm: ImmCSR, Opcode: SYSTEM_OPCODE, Func3: FUNC3_CSRRW, HasFunc3: true, Tag: TagCsrrw})
+	register(&InstrDesc{Name: "CSRRS", Format: FormatI, Imm: ImmCSR, Opcode: SYSTEM_OPCODE, Func3: FUNC3_CSRRS, HasFunc3: true, Tag: TagCsrrs})
+	register(&InstrDesc{Name: "CSRRC", Format: FormatI, Imm: ImmCSR, Opcode: SYSTEM_OPCODE, Func3: FUNC3_CSRRC, HasFunc3: true, Tag: TagCsrrc})
+	register(&InstrDesc{Name: "CSRRWI", Format: FormatI, Imm: ImmCSR, Opcode: SYSTEM_OPCODE, Func3: FUNC3_CSRRWI, HasFunc3: true, Tag: TagCsrrwi})
+	register(&InstrDesc{Name: "CSRRSI", Format: FormatI, Imm: ImmCSR, Opcode: SYSTEM_OPCODE, Func3: FUNC3_CSRRSI, HasFunc3: true, Tag: TagCsrrsi})
+	register(&InstrDesc{Name: "CSRRCI", Format: FormatI, Imm: ImmCSR, Opcode: SYSTEM_OPCODE, Func3: FUNC3_CSRRCI, HasFunc3: true, Tag: TagCsrrci})
+
+	register(&InstrDesc{Name: "LR.W", Format: FormatR, Opcode: AMO_OPCODE, Func3: AMO_FUNC3, HasFunc3: true, Func7: FUNCT5_LR << 2, HasFunc7: true, Func7Mask: 0b1111100, Tag: TagLR})
+	register(&InstrDesc{Name: "SC.W", Format: FormatR, Opcode: AMO_OPCODE, Func3: AMO_FUNC3, HasFunc3: true, Func7: FUNCT5_SC << 2, HasFunc7: true, Func7Mask: 0b1111100, Tag: TagSC})
+	register(&InstrDesc{Name: "AMOSWAP.W", Format: FormatR, Opcode: AMO_OPCODE, Func3: AMO_FUNC3, HasFunc3: true, Func7: FUNCT5_AMOSWAP << 2, HasFunc7: true, Func7Mask: 0b1111100, Tag: TagAmoSwap})
+	register(&InstrDesc{Name: "AMOADD.W", Format: FormatR, Opcode: AMO_OPCODE, Func3: AMO_FUNC3, HasFunc3: true, Func7: FUNCT5_AMOADD << 2, HasFunc7: true, Func7Mask: 0b1111100, Tag: TagAmoAdd})
+	register(&InstrDesc{Name: "AMOXOR.W", Format: FormatR, Opcode: AMO_OPCODE, Func3: AMO_FUNC3, HasFunc3: true, Func7: FUNCT5_AMOXOR << 2, HasFunc7: true, Func7Mask: 0b1111100, Tag: TagAmoXor})
+	register(&InstrDesc{Name: "AMOAND.W", Format: FormatR, Opcode: AMO_OPCODE, Func3: AMO_FUNC3, HasFunc3: true, Func7: FUNCT5_AMOAND << 2, HasFunc7: true, Func7Mask: 0b1111100, Tag: TagAmoAnd})
+	register(&InstrDesc{Name: "AMOOR.W", Format: FormatR, Opcode: AMO_OPCODE, Func3: AMO_FUNC3, HasFunc3: true, Func7: FUNCT5_AMOOR << 2, HasFunc7: true, Func7Mask: 0b1111100, Tag: TagAmoOr})
+	register(&InstrDesc{Name: "AMOMIN.W", Format: FormatR, Opcode: AMO_OPCODE, Func3: AMO_FUNC3, HasFunc3: true, Func7: FUNCT5_AMOMIN << 2, HasFunc7: true, Func7Mask: 0b1111100, Tag: TagAmoMin})
+	register(&InstrDesc{Name: "AMOMAX.W", Format: FormatR, Opcode: AMO_OPCODE, Func3: AMO_FUNC3, HasFunc3: true, Func7: FUNCT5_AMOMAX << 2, HasFunc7: true, Func7Mask: 0b1111100, Tag: TagAmoMax})
+	register(&InstrDesc{Name: "AMOMINU.W", Format: FormatR, Opcode: AMO_OPCODE, Func3: AMO_FUNC3, HasFunc3: true, Func7: FUNCT5_AMOMINU << 2, HasFunc7: true, Func7Mask: 0b1111100, Tag: TagAmoMinu})
+	register(&InstrDesc{Name: "AMOMAXU.W", Format: FormatR, Opcode: AMO_OPCODE, Func3: AMO_FUNC3, HasFunc3: true, Func7: FUNCT5_AMOMAXU << 2, HasFunc7: true, Func7Mask: 0b1111100, Tag: TagAmoMaxu})
+
+	register(&InstrDesc{Name: "FENCE", Format: FormatI, Opcode: FENCE_OPCODE, Func3: 0b000, HasFunc3: true, Tag: TagFence})
+	register(&InstrDesc{Name: "FENCE.I", Format: FormatI, Opcode: FENCE_OPCODE, Func3: 0b001, HasFunc3: true, Tag: TagFenceI})
+}
+
+// LookupDesc finds the InstrDesc matching a decoded opcode/funct3/funct7,
+// scanning the (usually tiny) opcode bucket rather than hashing all three
+// fields together, since U/J-type entries don't carry funct3/funct7 at all
+// and ECALL/EBREAK/MRET only diverge on imm12. systemImm is ignored unless
+// an entry in the bucket actually requires it.
+func LookupDesc(opcode, func3, func7 byte, systemImm int32) (*InstrDesc, bool) {
+	for _, d := range descsByOpcode[opcode] {
+		if d.HasFunc3 && d.Func3 != func3 {
+			continue
+		}
+		if d.HasFunc7 {
+			mask := d.Func7Mask
+			if mask == 0 {
+				mask = 0x7F
+			}
+			if d.Func7 != func7&mask {
+				continue
+			}
+		}
+		if d.HasSystemImm && d.SystemImm != systemImm {
+			continue
+		}
+		return d, true
+	}
+	return nil, false
+}
+
+// LookupDescByName finds the InstrDesc registered for a mnemonic, for
+// callers building an assembler on top of the table instead of calling a
+// per-mnemonic encoder function directly.
+func LookupDescByName(name string) (*InstrDesc, bool) {
+	d, ok := descsByName[name]
+	return d, ok
+}
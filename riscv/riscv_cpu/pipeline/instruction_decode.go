@@ -1,7 +1,7 @@
 package pipeline
 
 import (
-	"fmt"
+	"io"
 	. "riscv/system_interface"
 )
 
@@ -26,8 +26,22 @@ type DecodeStage struct {
 	isStoreOperation RBool
 	isLoadOperation  RBool
 	isLUIOperation   RBool
+	isAUIPCOperation RBool
 	isJALOperation   RBool
 	isJALROperation  RBool
+	isSystemOp       RBool
+	isMulDivOp       RBool
+	isAmoOp          RBool
+	amoFunct5        RByte
+	aq               RBool
+	rl               RBool
+	isFenceOp        RBool
+	isBranchOp       RBool
+	isECALL          RBool
+	isEBREAK         RBool
+	isMRET           RBool
+	isCSROp          RBool
+	isIllegalOp      RBool
 
 	opcode RByte // 7 bits [6-0]
 	rd     RByte // 5 bits [11-7]
@@ -38,6 +52,9 @@ type DecodeStage struct {
 	rs1V RUint32 // 5 bits [19-15]
 	rs2V RUint32 // 5 bits [24-20]
 
+	rs1Addr RByte
+	rs2Addr RByte
+
 	imm32 RInt32 // Sign-extend 12-bit immediate to 32 bits
 
 	branchAddress RUint32 // Calculated branch address
@@ -48,6 +65,8 @@ type DecodeStage struct {
 
 	shouldStall      func() bool
 	getInstructionIn func() FetchValues
+
+	tracer Tracer
 }
 
 func NewDecodeStage(params *DecodeParams) *DecodeStage {
@@ -57,9 +76,19 @@ func NewDecodeStage(params *DecodeParams) *DecodeStage {
 	ids.regFile = params.regFile
 	ids.shouldStall = params.shouldStall
 	ids.getInstructionIn = params.getFetchValuesIn
+	ids.tracer = NopTracer{}
 	return ids
 }
 
+// SetTracer attaches a Tracer to receive this stage's trace output. Pass
+// nil to go back to discarding it.
+func (ids *DecodeStage) SetTracer(tracer Tracer) {
+	if tracer == nil {
+		tracer = NopTracer{}
+	}
+	ids.tracer = tracer
+}
+
 func (ids *DecodeStage) Compute() {
 	if !ids.shouldStall() {
 		fv := ids.getInstructionIn()
@@ -76,19 +105,35 @@ func (ids *DecodeStage) Compute() {
 		ids.isStoreOperation.SetN(opcode == 0b0100011)
 		ids.isLoadOperation.SetN(opcode == 0b0000011)
 		ids.isLUIOperation.SetN(opcode == 0b0110111)
+		ids.isAUIPCOperation.SetN(opcode == 0b0010111)
 		ids.isJALOperation.SetN(opcode == JAL_OPCODE)
 		ids.isJALROperation.SetN(opcode == JALR_OPCODE)
+		ids.isSystemOp.SetN(opcode == SYSTEM_OPCODE)
 
 		ids.rd.SetN(byte((ins >> 7) & 0x1F))
 
 		ids.func3.SetN(byte((ins >> 12) & 0x07))
 		ids.func7.SetN(byte((ins >> 25) & 0x7F))
 
+		ids.isMulDivOp.SetN(opcode == REGISTER_OPCODE && ids.func7.GetN() == FUNC7_MULDIV)
+
+		ids.isAmoOp.SetN(opcode == AMO_OPCODE)
+		ids.amoFunct5.SetN(ids.func7.GetN() >> 2)
+		ids.aq.SetN(ids.func7.GetN()&0b10 != 0)
+		ids.rl.SetN(ids.func7.GetN()&0b01 != 0)
+
+		ids.isFenceOp.SetN(opcode == FENCE_OPCODE)
+
+		ids.isBranchOp.SetN(opcode == BRANCH_OPCODE)
+
 		rs1Address := byte((ins >> 15) & 0x1F)
 		rs2Address := byte((ins >> 20) & 0x1F)
 
 		ids.shamt.SetN(rs2Address) // For shift instructions, shamt is in rs2 field
 
+		ids.rs1Addr.SetN(rs1Address)
+		ids.rs2Addr.SetN(rs2Address)
+
 		ids.rs1V.SetN(0)
 		if rs1Address != 0 {
 			ids.rs1V.SetN(ids.regFile[rs1Address].GetN())
@@ -104,27 +149,59 @@ func (ids *DecodeStage) Compute() {
 		imm_4_0 := int32((ins >> 7) & 0x1F)
 		imm_11_5 := (int32((ins >> 25) & 0x7F))
 
-		sImm := (imm_11_5 << 5) | imm_4_0
+		sImm := ((imm_11_5 << 5) | imm_4_0) << 20 >> 20
 		iImm := (imm_11_0 << 20) >> 20
 		uImm := ins & 0xFFFFF000
 
+		// Immediate extraction for B-type instructions: imm[12|11|10:5|4:1],
+		// LSB implicitly 0, sign-extended from 13 bits.
+		bImm_12 := int32((ins >> 31) & 0x1)
+		bImm_11 := int32((ins >> 7) & 0x1)
+		bImm_10_5 := int32((ins >> 25) & 0x3F)
+		bImm_4_1 := int32((ins >> 8) & 0xF)
+		bImm := (bImm_12 << 12) | (bImm_11 << 11) | (bImm_10_5 << 5) | (bImm_4_1 << 1)
+		bImm = (bImm << 19) >> 19
+
 		jins := JTypeDecode(ins)
 
+		ids.isIllegalOp.SetN(false)
+
 		if ids.isStoreOperation.GetN() {
 			ids.imm32.SetN(sImm)
 		} else if ids.isAluOperation.GetN() || ids.isLoadOperation.GetN() {
 			ids.imm32.SetN(iImm)
 		} else if ids.isLUIOperation.GetN() {
 			ids.imm32.SetN(int32(uImm))
+		} else if ids.isAUIPCOperation.GetN() {
+			ids.imm32.SetN(int32(uImm))
 		} else if ids.isJALOperation.GetN() {
-			ids.imm32.SetN(jins.imm32)
-			ids.branchAddress.SetN(uint32(int32(fv.pc) + jins.imm32))
+			ids.imm32.SetN(jins.Imm)
+			ids.branchAddress.SetN(uint32(int32(fv.pc) + jins.Imm))
 		} else if ids.isJALROperation.GetN() {
 			ids.imm32.SetN(iImm)
 			ids.branchAddress.SetN(uint32(int32(ids.rs1V.GetN()) + iImm))
+		} else if ids.isSystemOp.GetN() {
+			ids.imm32.SetN(int32(ins >> 20)) // CSR address (unsigned, not sign-extended)
+			ids.isECALL.SetN(ids.func3.GetN() == 0 && imm_11_0 == SYSTEM_IMM_ECALL)
+			ids.isEBREAK.SetN(ids.func3.GetN() == 0 && imm_11_0 == SYSTEM_IMM_EBREAK)
+			ids.isMRET.SetN(ids.func3.GetN() == 0 && imm_11_0 == SYSTEM_IMM_MRET)
+			ids.isCSROp.SetN(ids.func3.GetN() != 0)
+		} else if ids.isAmoOp.GetN() {
+			ids.imm32.SetN(0) // AMO/LR/SC address is x[rs1] with no offset
+		} else if ids.isFenceOp.GetN() {
+			ids.imm32.SetN(imm_11_0) // pred/succ/fm bits; unused in this single-hart model
+		} else if ids.isBranchOp.GetN() {
+			ids.imm32.SetN(bImm)
+			ids.branchAddress.SetN(uint32(int32(fv.pc) + bImm))
 		} else {
-			panic(fmt.Sprintf("Unknown operation 0x%x", ins))
+			// A reserved/unimplemented opcode: don't crash the process, let
+			// ExecuteStage's isIllegalOp latch carry this to cpu.go's
+			// handleIllegalInstruction the same way a SYSTEM trap reaches
+			// handleTraps.
+			ids.isIllegalOp.SetN(true)
 		}
+
+		ids.tracer.OnDecode(ids.GetDecodedValuesOut())
 	}
 }
 
@@ -136,8 +213,22 @@ func (ids *DecodeStage) LatchNext() {
 	ids.isStoreOperation.LatchNext()
 	ids.isLoadOperation.LatchNext()
 	ids.isLUIOperation.LatchNext()
+	ids.isAUIPCOperation.LatchNext()
 	ids.isJALOperation.LatchNext()
 	ids.isJALROperation.LatchNext()
+	ids.isSystemOp.LatchNext()
+	ids.isMulDivOp.LatchNext()
+	ids.isAmoOp.LatchNext()
+	ids.amoFunct5.LatchNext()
+	ids.aq.LatchNext()
+	ids.rl.LatchNext()
+	ids.isFenceOp.LatchNext()
+	ids.isBranchOp.LatchNext()
+	ids.isECALL.LatchNext()
+	ids.isEBREAK.LatchNext()
+	ids.isMRET.LatchNext()
+	ids.isCSROp.LatchNext()
+	ids.isIllegalOp.LatchNext()
 
 	ids.rd.LatchNext()
 
@@ -146,6 +237,8 @@ func (ids *DecodeStage) LatchNext() {
 
 	ids.rs1V.LatchNext()
 	ids.rs2V.LatchNext()
+	ids.rs1Addr.LatchNext()
+	ids.rs2Addr.LatchNext()
 	ids.shamt.LatchNext()
 
 	ids.imm32.LatchNext()
@@ -155,52 +248,262 @@ func (ids *DecodeStage) LatchNext() {
 	ids.pcPlus4.LatchNext()
 }
 
+// Serialize writes the DE latch registers that have a Serialize method
+// (the RUint32/RInt32/RByte ones; the RBool flags aren't snapshotted, and
+// Deserialize rederives them from the restored latches via
+// recomputeFlags). Order must match Deserialize.
+func (ids *DecodeStage) Serialize(w io.Writer) error {
+	if err := ids.instruction.Serialize(w); err != nil {
+		return err
+	}
+	if err := ids.opcode.Serialize(w); err != nil {
+		return err
+	}
+	if err := ids.rd.Serialize(w); err != nil {
+		return err
+	}
+	if err := ids.func3.Serialize(w); err != nil {
+		return err
+	}
+	if err := ids.func7.Serialize(w); err != nil {
+		return err
+	}
+	if err := ids.amoFunct5.Serialize(w); err != nil {
+		return err
+	}
+	if err := ids.rs1V.Serialize(w); err != nil {
+		return err
+	}
+	if err := ids.rs2V.Serialize(w); err != nil {
+		return err
+	}
+	if err := ids.rs1Addr.Serialize(w); err != nil {
+		return err
+	}
+	if err := ids.rs2Addr.Serialize(w); err != nil {
+		return err
+	}
+	if err := ids.shamt.Serialize(w); err != nil {
+		return err
+	}
+	if err := ids.imm32.Serialize(w); err != nil {
+		return err
+	}
+	if err := ids.branchAddress.Serialize(w); err != nil {
+		return err
+	}
+	if err := ids.pc.Serialize(w); err != nil {
+		return err
+	}
+	return ids.pcPlus4.Serialize(w)
+}
+
+// Deserialize restores the DE latch registers written by Serialize.
+func (ids *DecodeStage) Deserialize(r io.Reader) error {
+	if err := ids.instruction.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ids.opcode.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ids.rd.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ids.func3.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ids.func7.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ids.amoFunct5.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ids.rs1V.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ids.rs2V.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ids.rs1Addr.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ids.rs2Addr.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ids.shamt.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ids.imm32.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ids.branchAddress.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ids.pc.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ids.pcPlus4.Deserialize(r); err != nil {
+		return err
+	}
+	ids.recomputeFlags()
+	return nil
+}
+
+// recomputeFlags rederives the RBool flags Serialize skips (isAluOp etc.)
+// from the opcode/func3/func7/imm32 Deserialize just restored, the same
+// mapping Compute uses when it first decodes an instruction. A restored
+// mid-pipeline snapshot has no upcoming Compute call to do this for the
+// instruction already latched in DE, so Deserialize has to do it instead.
+func (ids *DecodeStage) recomputeFlags() {
+	opcode := ids.opcode.Get()
+	func3 := ids.func3.Get()
+	func7 := ids.func7.Get()
+	imm32 := ids.imm32.Get()
+
+	set := func(r *RBool, v bool) {
+		r.SetN(v)
+		r.LatchNext()
+	}
+
+	set(&ids.isAluOperation, opcode&0b1011111 == 0b0010011)
+	set(&ids.isStoreOperation, opcode == 0b0100011)
+	set(&ids.isLoadOperation, opcode == 0b0000011)
+	set(&ids.isLUIOperation, opcode == 0b0110111)
+	set(&ids.isAUIPCOperation, opcode == 0b0010111)
+	set(&ids.isJALOperation, opcode == JAL_OPCODE)
+	set(&ids.isJALROperation, opcode == JALR_OPCODE)
+	set(&ids.isSystemOp, opcode == SYSTEM_OPCODE)
+	set(&ids.isMulDivOp, opcode == REGISTER_OPCODE && func7 == FUNC7_MULDIV)
+	set(&ids.isAmoOp, opcode == AMO_OPCODE)
+	set(&ids.aq, func7&0b10 != 0)
+	set(&ids.rl, func7&0b01 != 0)
+	set(&ids.isFenceOp, opcode == FENCE_OPCODE)
+	set(&ids.isBranchOp, opcode == BRANCH_OPCODE)
+
+	isSystemOp := ids.isSystemOp.Get()
+	set(&ids.isECALL, isSystemOp && func3 == 0 && imm32 == SYSTEM_IMM_ECALL)
+	set(&ids.isEBREAK, isSystemOp && func3 == 0 && imm32 == SYSTEM_IMM_EBREAK)
+	set(&ids.isMRET, isSystemOp && func3 == 0 && imm32 == SYSTEM_IMM_MRET)
+	set(&ids.isCSROp, isSystemOp && func3 != 0)
+
+	recognized := ids.isAluOperation.Get() || ids.isStoreOperation.Get() ||
+		ids.isLoadOperation.Get() || ids.isLUIOperation.Get() ||
+		ids.isAUIPCOperation.Get() || ids.isJALOperation.Get() ||
+		ids.isJALROperation.Get() || isSystemOp || ids.isAmoOp.Get() ||
+		ids.isFenceOp.Get() || ids.isBranchOp.Get()
+	set(&ids.isIllegalOp, !recognized)
+}
+
+// DecodedValues fields are all unexported: nothing outside package pipeline
+// ever reads one, the same convention ExecutedValues/MemoryAccessValues
+// already use.
 type DecodedValues struct {
-	Opcode           byte
-	IsAluOperation   bool
-	IsStoreOperation bool
-	IsLoadOperation  bool
-	isLUIOperation   bool
-	IsJALOperation   bool
-	IsJALROperation  bool
-
-	Rd      byte
-	Func3   byte
-	Func7   byte
-	Rs1V    uint32
-	Rs2V    uint32
-	Rs1Addr byte
-	Rs2Addr byte
-	Shamt   byte
-
-	Imm32 int32
-
-	BranchAddress uint32
+	insn        uint32
+	opcode      byte
+	isAluOp     bool
+	isStoreOp   bool
+	isLoadOp    bool
+	isLuiOp     bool
+	isAuipcOp   bool
+	isJalOp     bool
+	isJalrOp    bool
+	isSystemOp  bool
+	isMulDivOp  bool
+	isAmoOp     bool
+	amoFunct5   byte
+	aq          bool
+	rl          bool
+	isFenceOp   bool
+	isBranchOp  bool
+	isEcall     bool
+	isEbreak    bool
+	isMret      bool
+	isCsrOp     bool
+	isIllegalOp bool
+
+	rd      byte
+	func3   byte
+	func7   byte
+	rs1V    uint32
+	rs2V    uint32
+	rs1Addr byte
+	rs2Addr byte
+	shamt   byte
+
+	imm32 int32
+
+	branchAddress uint32
 	pc            uint32
 	pcPlus4       uint32
+
+	// isFusedOp/fusedTag are set by FuserStage, never by DecodeStage
+	// itself: a plain decode always comes back with isFusedOp false.
+	isFusedOp bool
+	fusedTag  SemanticTag
 }
 
+// GetDecodedValuesOut returns this stage's latched output: what DE finished
+// decoding as of the end of the previous cycle, the value EX/Fuser consume
+// as their input this cycle. Use ids.instruction.GetN()-style accessors
+// instead if you ever need this cycle's still-in-flight decode (nothing
+// outside DE currently does).
 func (ids *DecodeStage) GetDecodedValuesOut() DecodedValues {
 	return DecodedValues{
-		Opcode:           ids.opcode.GetN(),
-		IsAluOperation:   ids.isAluOperation.GetN(),
-		IsStoreOperation: ids.isStoreOperation.GetN(),
-		IsLoadOperation:  ids.isLoadOperation.GetN(),
-		isLUIOperation:   ids.isLUIOperation.GetN(),
-		IsJALOperation:   ids.isJALOperation.GetN(),
-		IsJALROperation:  ids.isJALROperation.GetN(),
-
-		Rd:    ids.rd.GetN(),
-		Func3: ids.func3.GetN(),
-		Func7: ids.func7.GetN(),
-		Rs1V:  ids.rs1V.GetN(),
-		Rs2V:  ids.rs2V.GetN(),
-		Shamt: ids.shamt.GetN(),
-
-		Imm32: ids.imm32.GetN(),
-
-		BranchAddress: ids.branchAddress.GetN(),
-		pc:            ids.pc.GetN(),
-		pcPlus4:       ids.pcPlus4.GetN(),
+		insn:        ids.instruction.Get(),
+		opcode:      ids.opcode.Get(),
+		isAluOp:     ids.isAluOperation.Get(),
+		isStoreOp:   ids.isStoreOperation.Get(),
+		isLoadOp:    ids.isLoadOperation.Get(),
+		isLuiOp:     ids.isLUIOperation.Get(),
+		isAuipcOp:   ids.isAUIPCOperation.Get(),
+		isJalOp:     ids.isJALOperation.Get(),
+		isJalrOp:    ids.isJALROperation.Get(),
+		isSystemOp:  ids.isSystemOp.Get(),
+		isMulDivOp:  ids.isMulDivOp.Get(),
+		isAmoOp:     ids.isAmoOp.Get(),
+		amoFunct5:   ids.amoFunct5.Get(),
+		aq:          ids.aq.Get(),
+		rl:          ids.rl.Get(),
+		isFenceOp:   ids.isFenceOp.Get(),
+		isBranchOp:  ids.isBranchOp.Get(),
+		isEcall:     ids.isECALL.Get(),
+		isEbreak:    ids.isEBREAK.Get(),
+		isMret:      ids.isMRET.Get(),
+		isCsrOp:     ids.isCSROp.Get(),
+		isIllegalOp: ids.isIllegalOp.Get(),
+
+		rd:    ids.rd.Get(),
+		func3: ids.func3.Get(),
+		func7: ids.func7.Get(),
+		rs1V:  ids.rs1V.Get(),
+		rs2V:  ids.rs2V.Get(),
+		shamt: ids.shamt.Get(),
+
+		rs1Addr: ids.rs1Addr.Get(),
+		rs2Addr: ids.rs2Addr.Get(),
+
+		imm32: ids.imm32.Get(),
+
+		branchAddress: ids.branchAddress.Get(),
+		pc:            ids.pc.Get(),
+		pcPlus4:       ids.pcPlus4.Get(),
 	}
 }
+
+// The accessors below expose the handful of DecodedValues fields
+// cpu.go's trap handling needs from outside package pipeline: that code
+// lives in package riscv, so the all-unexported fields DecodedValues
+// normalized to (see the "normalize DecodedValues" fix) aren't reachable
+// directly.
+func (dv DecodedValues) IsSystemOp() bool { return dv.isSystemOp }
+func (dv DecodedValues) IsCsrOp() bool    { return dv.isCsrOp }
+func (dv DecodedValues) IsEcall() bool    { return dv.isEcall }
+func (dv DecodedValues) IsEbreak() bool   { return dv.isEbreak }
+func (dv DecodedValues) IsMret() bool     { return dv.isMret }
+func (dv DecodedValues) Insn() uint32     { return dv.insn }
+func (dv DecodedValues) Rd() byte         { return dv.rd }
+func (dv DecodedValues) Func3() byte      { return dv.func3 }
+func (dv DecodedValues) Rs1V() uint32     { return dv.rs1V }
+func (dv DecodedValues) Rs1Addr() byte    { return dv.rs1Addr }
+func (dv DecodedValues) Imm32() int32     { return dv.imm32 }
+func (dv DecodedValues) PcPlus4() uint32  { return dv.pcPlus4 }
@@ -1,7 +1,7 @@
 package pipeline
 
 import (
-	"fmt"
+	"io"
 	. "riscv/system_interface"
 )
 
@@ -33,27 +33,45 @@ const (
 type ExecuteStage struct {
 	aluResult RUint32
 
-	rd   RByte
-	rs1V RUint32
-	rs2V RUint32
-
-	isAluOp   RBool
-	isStoreOp RBool
-	isLoadOp  RBool
-	isLuiOp   RBool
-	isJumpOp  RBool
+	insn    RUint32
+	rd      RByte
+	rs1Addr RByte
+	rs2Addr RByte
+	rs1V    RUint32
+	rs2V    RUint32
+	pc      RUint32
+
+	isAluOp     RBool
+	isStoreOp   RBool
+	isLoadOp    RBool
+	isLuiOp     RBool
+	isJumpOp    RBool
+	isMulDivOp  RBool
+	isAmoOp     RBool
+	amoTag      RByte
+	isIllegalOp RBool
 
 	imm32 RInt32
 	func3 RByte
 
 	pcPlus4       RUint32
+	pcNext        RUint32
 	branchAddress RUint32
 	branchValid   RBool
 
+	shiftAmount RByte // shift amount actually applied, for ShiftPerShamt costing
+
 	regFile *[32]RUint32
 
 	shouldStall        func() bool
 	getDecodedValuesIn func() DecodedValues
+
+	forwardIn   func() (rd byte, value uint32, valid bool)
+	forwardInWB func() (rd byte, value uint32, valid bool)
+
+	cycleCost CycleCost
+
+	tracer Tracer
 }
 
 func NewExecuteStage(params *ExecuteParams) *ExecuteStage {
@@ -64,247 +82,677 @@ func NewExecuteStage(params *ExecuteParams) *ExecuteStage {
 
 	ies.shouldStall = params.shouldStall
 	ies.getDecodedValuesIn = params.getDecodedValuesIn
+	ies.forwardIn = func() (byte, uint32, bool) { return 0, 0, false }
+	ies.forwardInWB = func() (byte, uint32, bool) { return 0, 0, false }
+	ies.cycleCost = DefaultCycleCost
+	ies.tracer = NopTracer{}
 	return ies
 }
 
+// SetTracer attaches a Tracer to receive this stage's trace output. Pass
+// nil to go back to discarding it.
+func (ies *ExecuteStage) SetTracer(tracer Tracer) {
+	if tracer == nil {
+		tracer = NopTracer{}
+	}
+	ies.tracer = tracer
+}
+
+// SetCycleCost overrides the per-instruction cycle-cost table ExecuteStage
+// consults when GetExecuteCycles is read. Pass the zero value to charge no
+// extra cycles at all.
+func (ies *ExecuteStage) SetCycleCost(cost CycleCost) {
+	ies.cycleCost = cost
+}
+
+// GetExecuteCycles returns the extra cycles the instruction currently
+// latched in EXECUTE burns, on top of the one cycle every Cycle() call
+// already charges: a bubble for a taken branch/jump, and optionally a
+// shamt-scaled charge for shift instructions.
+func (ies *ExecuteStage) GetExecuteCycles() uint64 {
+	var cost uint64
+	if ies.branchValid.GetN() {
+		cost += ies.cycleCost.BranchTakenBubble
+	}
+	if ies.cycleCost.ShiftPerShamt && (ies.func3.GetN() == OP_SLL || ies.func3.GetN() == OP_SRL) {
+		cost += uint64(ies.shiftAmount.GetN())
+	}
+	return cost
+}
+
+// GetOperandsOut returns the rs1/rs2 values this Compute call resolved for
+// the instruction now in EXECUTE, after the SetForwardSource bypass is
+// applied — unlike GetExecutionValuesOut (latched, one cycle stale),
+// GetN() exposes them the same cycle, for cpu.go's handleTraps to read a
+// CSRRW/CSRRS/CSRRC source register that a still-in-flight prior
+// instruction just produced, instead of DecodeStage's pre-forward
+// snapshot.
+func (ies *ExecuteStage) GetOperandsOut() (rs1V, rs2V uint32) {
+	return ies.rs1V.GetN(), ies.rs2V.GetN()
+}
+
+// IsIllegalOp reports this Compute call's illegal-op verdict for the
+// instruction now in EXECUTE — unlike GetExecutionValuesOut().IsIllegalOp()
+// (latched, one cycle stale), GetN() exposes it the same cycle, for cpu.go's
+// exRan handling to check the instruction EX just computed instead of the
+// one before it.
+func (ies *ExecuteStage) IsIllegalOp() bool {
+	return ies.isIllegalOp.GetN()
+}
+
+// SetForwardSource attaches a bypass source ExecuteStage consults for rs1/rs2
+// before reading the latched regFile values, so a load or ALU result still
+// sitting in the MA latch can feed a dependent instruction without waiting
+// for it to reach WriteBackStage. Pass nil to go back to no forwarding.
+func (ies *ExecuteStage) SetForwardSource(forwardIn func() (rd byte, value uint32, valid bool)) {
+	if forwardIn == nil {
+		forwardIn = func() (byte, uint32, bool) { return 0, 0, false }
+	}
+	ies.forwardIn = forwardIn
+}
+
+// SetForwardSourceWB attaches a second bypass source, consulted before the
+// MA one so MA wins on a matching rd, for a producer that has already left
+// MA and committed via WriteBackStage by the time this instruction reaches
+// EX (a write in cycle N observed by a use in cycle N+2). MA must stay the
+// tiebreaker: WB and MA can report the same rd valid in the same cycle (the
+// classic LUI+ADDI 32-bit-immediate idiom retires the LUI through WB the
+// same cycle the ADDI's fresher result sits in MA), and MA is always the
+// more recent producer when that happens. Pass nil to go back to no
+// forwarding.
+func (ies *ExecuteStage) SetForwardSourceWB(forwardInWB func() (rd byte, value uint32, valid bool)) {
+	if forwardInWB == nil {
+		forwardInWB = func() (byte, uint32, bool) { return 0, 0, false }
+	}
+	ies.forwardInWB = forwardInWB
+}
+
 func (ies *ExecuteStage) Compute() {
 	if !ies.shouldStall() {
-		// fmt.Println("@ EXECUTE")
-
 		decoded := ies.getDecodedValuesIn()
 
+		rs1V := decoded.rs1V
+		rs2V := decoded.rs2V
+		// WB is consulted before MA so MA wins when both target the same rd:
+		// MA is always the more recent producer, even though WB is the only
+		// source that can resolve a genuine two-apart-only hazard.
+		if fwdRd, fwdValue, fwdValid := ies.forwardInWB(); fwdValid && fwdRd != 0 {
+			if fwdRd == decoded.rs1Addr {
+				rs1V = fwdValue
+			}
+			if fwdRd == decoded.rs2Addr {
+				rs2V = fwdValue
+			}
+		}
+		if fwdRd, fwdValue, fwdValid := ies.forwardIn(); fwdValid && fwdRd != 0 {
+			if fwdRd == decoded.rs1Addr {
+				rs1V = fwdValue
+			}
+			if fwdRd == decoded.rs2Addr {
+				rs2V = fwdValue
+			}
+		}
+
+		// Look up this instruction's InstrDesc instead of re-deriving
+		// isRegisterOp/isAlternate from raw opcode/funct7 bits: the table
+		// already knows the format and the SUB-vs-ADD distinction.
+		desc, descFound := LookupDesc(decoded.opcode, decoded.func3, decoded.func7, decoded.imm32)
+		isRegisterOp := descFound && desc.Format == FormatR
+
+		// isJumpOp covers both JAL and JALR; isJalOp narrows it to JAL alone,
+		// for the branchBase choice below (JAL targets pc-relative, JALR
+		// targets rs1-relative).
+		isJumpOp := decoded.isJalOp || decoded.isJalrOp
+
 		ies.isAluOp.SetN(decoded.isAluOp)
 		ies.isStoreOp.SetN(decoded.isStoreOp)
 		ies.isLoadOp.SetN(decoded.isLoadOp)
 		ies.isLuiOp.SetN(decoded.isLuiOp)
-		ies.isJumpOp.SetN(decoded.IsJumpOp)
+		ies.isJumpOp.SetN(isJumpOp)
+		ies.isMulDivOp.SetN(decoded.isMulDivOp)
+		ies.isAmoOp.SetN(decoded.isAmoOp)
+		if descFound {
+			ies.amoTag.SetN(byte(desc.Tag))
+		}
+
+		// A reserved opcode DecodeStage couldn't classify, or an ALU
+		// encoding descFound doesn't resolve, both mean EX has no result to
+		// produce: flag it instead of leaving aluResult holding a stale
+		// value from whatever instruction last wrote it, so cpu.go's
+		// handleIllegalInstruction can trap on it.
+		ies.isIllegalOp.SetN(decoded.isIllegalOp || (decoded.isAluOp && !descFound))
 
 		ies.pcPlus4.SetN(decoded.pcPlus4)
 
-		ies.imm32.SetN(decoded.imm)
+		ies.imm32.SetN(decoded.imm32)
 		ies.func3.SetN(decoded.func3)
 
-		imm32 := decoded.imm
+		imm32 := decoded.imm32
 
 		// Save destination register for write-back in the latch phase
 		ies.rd.SetN(decoded.rd)
-		ies.rs1V.SetN(decoded.rs1V)
-		ies.rs2V.SetN(decoded.rs2V)
-
-		isRegisterOp := decoded.opcode>>5 == 1     // Check if opcode indicates register-register operation
-		isAlternate := (decoded.func7 & 0x20) != 0 // Use funct7 bit to distinguish SUB (0100000)
-
-		if decoded.IsJumpOp || decoded.isBranchOp {
-			branchBase := decoded.rs1V
-			if decoded.IsJalOp || decoded.isBranchOp {
+		ies.rs1V.SetN(rs1V)
+		ies.rs2V.SetN(rs2V)
+
+		// Carried through to MA/WB purely for RVFI retire records: by the
+		// time WB retires this instruction, DE/EX have moved on to later
+		// ones, so RetireRecord can no longer read these off DE's output.
+		ies.insn.SetN(decoded.insn)
+		ies.rs1Addr.SetN(decoded.rs1Addr)
+		ies.rs2Addr.SetN(decoded.rs2Addr)
+		ies.pc.SetN(decoded.pc)
+
+		if isJumpOp || decoded.isBranchOp {
+			branchBase := rs1V
+			if decoded.isJalOp || decoded.isBranchOp {
 				branchBase = decoded.pc
 			}
 			ies.branchAddress.SetN(branchBase + uint32(imm32))
 
-			fmt.Printf(" target=0x%08X ", ies.branchAddress.GetN())
+			ies.tracer.Printf(" target=0x%08X ", ies.branchAddress.GetN())
 
 			var branchConditionMet = false
 
 			if decoded.isBranchOp {
 				switch decoded.func3 {
 				case FUNC3_BEQ:
-					branchConditionMet = decoded.rs1V == decoded.rs2V
-					fmt.Printf(" Branch BEQ  Rs1v=0x%08X  Rs2v=0x%08X -> %v\n", decoded.rs1V, decoded.rs2V, branchConditionMet)
+					branchConditionMet = rs1V == rs2V
+					ies.tracer.Printf(" Branch BEQ  Rs1v=0x%08X  Rs2v=0x%08X -> %v\n", rs1V, rs2V, branchConditionMet)
 				case FUNC3_BNE:
-					branchConditionMet = decoded.rs1V != decoded.rs2V
-					fmt.Printf(" Branch BNE  Rs1v=0x%08X  Rs2v=0x%08X -> %v\n", decoded.rs1V, decoded.rs2V, branchConditionMet)
+					branchConditionMet = rs1V != rs2V
+					ies.tracer.Printf(" Branch BNE  Rs1v=0x%08X  Rs2v=0x%08X -> %v\n", rs1V, rs2V, branchConditionMet)
 				case FUNC3_BLT:
-					branchConditionMet = int32(decoded.rs1V) < int32(decoded.rs2V)
-					fmt.Printf(" Branch BLT  Rs1v=0x%08X  Rs2v=0x%08X -> %v\n", decoded.rs1V, decoded.rs2V, branchConditionMet)
+					branchConditionMet = int32(rs1V) < int32(rs2V)
+					ies.tracer.Printf(" Branch BLT  Rs1v=0x%08X  Rs2v=0x%08X -> %v\n", rs1V, rs2V, branchConditionMet)
 				case FUNC3_BGE:
-					branchConditionMet = int32(decoded.rs1V) >= int32(decoded.rs2V)
-					fmt.Printf(" Branch BGE  Rs1v=0x%08X  Rs2v=0x%08X -> %v\n", decoded.rs1V, decoded.rs2V, branchConditionMet)
+					branchConditionMet = int32(rs1V) >= int32(rs2V)
+					ies.tracer.Printf(" Branch BGE  Rs1v=0x%08X  Rs2v=0x%08X -> %v\n", rs1V, rs2V, branchConditionMet)
 				case FUNC3_BLTU:
-					branchConditionMet = decoded.rs1V < decoded.rs2V
-					fmt.Printf(" Branch BLTU Rs1v=0x%08X  Rs2v=0x%08X -> %v\n", decoded.rs1V, decoded.rs2V, branchConditionMet)
+					branchConditionMet = rs1V < rs2V
+					ies.tracer.Printf(" Branch BLTU Rs1v=0x%08X  Rs2v=0x%08X -> %v\n", rs1V, rs2V, branchConditionMet)
 				case FUNC3_BGEU:
-					branchConditionMet = decoded.rs1V >= decoded.rs2V
-					fmt.Printf(" Branch BGEU Rs1v=0x%08X  Rs2v=0x%08X -> %v\n", decoded.rs1V, decoded.rs2V, branchConditionMet)
+					branchConditionMet = rs1V >= rs2V
+					ies.tracer.Printf(" Branch BGEU Rs1v=0x%08X  Rs2v=0x%08X -> %v\n", rs1V, rs2V, branchConditionMet)
 				}
 			}
 
-			ies.branchValid.SetN(decoded.IsJumpOp || (decoded.isBranchOp && branchConditionMet))
+			ies.branchValid.SetN(isJumpOp || (decoded.isBranchOp && branchConditionMet))
 		} else {
 			ies.branchValid.SetN(false)
 		}
 
-		if decoded.isAluOp {
-
-			// Perform ALU operation
-			switch decoded.func3 {
-			case OP_ADD_SUB:
-				{
-					if isRegisterOp {
-						if isAlternate {
-							ies.aluResult.SetN(decoded.rs1V - decoded.rs2V)
-							fmt.Printf(" SUB   Rd=%02d  Rs1v=0x%08X  Rs2v=0x%08X -> 0x%08X", decoded.rd, decoded.rs1V, decoded.rs2V, ies.aluResult.GetN())
-						} else {
-							ies.aluResult.SetN(decoded.rs1V + decoded.rs2V)
-							fmt.Printf(" ADD   Rd=%02d  Rs1v=0x%08X  Rs2v=0x%08X -> 0x%08X", decoded.rd, decoded.rs1V, decoded.rs2V, ies.aluResult.GetN())
-						}
-					} else {
-						ies.aluResult.SetN(decoded.rs1V + uint32(imm32))
-						fmt.Printf(" ADDI  Rd=%02d  Rs1v=0x%08X  imm=0x%08X -> 0x%08X", decoded.rd, decoded.rs1V, imm32, ies.aluResult.GetN())
-					}
+		// pcNext is this instruction's own resulting PC, for RVFI's
+		// PcWdata: the branch target if it redirected control flow, pc+4
+		// otherwise, carried through MA/WB the same way pc/insn are.
+		if ies.branchValid.GetN() {
+			ies.pcNext.SetN(ies.branchAddress.GetN())
+		} else {
+			ies.pcNext.SetN(decoded.pcPlus4)
+		}
+
+		if decoded.isMulDivOp {
+			// RV32M: funct7 0000001 shares REGISTER_OPCODE with the base
+			// ALU R-type ops, so it dispatches on SemanticTag the same
+			// way the ADD/SUB split does, rather than adding a second
+			// isAlternate-style bit check.
+			switch desc.Tag {
+			case TagMul:
+				ies.aluResult.SetN(uint32(int32(rs1V) * int32(rs2V)))
+				ies.tracer.Printf(" MUL    Rd=%02d  Rs1v=0x%08X  Rs2v=0x%08X -> 0x%08X", decoded.rd, rs1V, rs2V, ies.aluResult.GetN())
+			case TagMulh:
+				product := int64(int32(rs1V)) * int64(int32(rs2V))
+				ies.aluResult.SetN(uint32(product >> 32))
+				ies.tracer.Printf(" MULH   Rd=%02d  Rs1v=0x%08X  Rs2v=0x%08X -> 0x%08X", decoded.rd, rs1V, rs2V, ies.aluResult.GetN())
+			case TagMulhsu:
+				product := int64(int32(rs1V)) * int64(uint64(rs2V))
+				ies.aluResult.SetN(uint32(product >> 32))
+				ies.tracer.Printf(" MULHSU Rd=%02d  Rs1v=0x%08X  Rs2v=0x%08X -> 0x%08X", decoded.rd, rs1V, rs2V, ies.aluResult.GetN())
+			case TagMulhu:
+				product := uint64(rs1V) * uint64(rs2V)
+				ies.aluResult.SetN(uint32(product >> 32))
+				ies.tracer.Printf(" MULHU  Rd=%02d  Rs1v=0x%08X  Rs2v=0x%08X -> 0x%08X", decoded.rd, rs1V, rs2V, ies.aluResult.GetN())
+			case TagDiv:
+				dividend, divisor := int32(rs1V), int32(rs2V)
+				switch {
+				case divisor == 0:
+					ies.aluResult.SetN(0xFFFFFFFF)
+				case dividend == int32(-2147483648) && divisor == -1:
+					ies.aluResult.SetN(uint32(dividend))
+				default:
+					ies.aluResult.SetN(uint32(dividend / divisor))
 				}
-			case OP_SLL:
-				{
-					if isRegisterOp {
-						shiftAmount := decoded.rs2V & 0x1F
-						ies.aluResult.SetN(decoded.rs1V << shiftAmount)
-						fmt.Printf(" SLL   Rd=%02d  Rs1v=0x%08X  Rs2v=0x%08X -> 0x%08X", decoded.rd, decoded.rs1V, decoded.rs2V, ies.aluResult.GetN())
-					} else {
-						shiftAmount := decoded.shamt & 0x1F
-						ies.aluResult.SetN(decoded.rs1V << shiftAmount)
-						fmt.Printf(" SLLI  Rd=%02d  Rs1v=0x%08X  sha=0x%08X -> 0x%08X", decoded.rd, decoded.rs1V, shiftAmount, ies.aluResult.GetN())
-					}
+				ies.tracer.Printf(" DIV    Rd=%02d  Rs1v=0x%08X  Rs2v=0x%08X -> 0x%08X", decoded.rd, rs1V, rs2V, ies.aluResult.GetN())
+			case TagDivu:
+				if rs2V == 0 {
+					ies.aluResult.SetN(0xFFFFFFFF)
+				} else {
+					ies.aluResult.SetN(rs1V / rs2V)
 				}
-			case OP_SLT:
-				{
-					if isRegisterOp {
-						if int32(decoded.rs1V) < int32(decoded.rs2V) {
-							ies.aluResult.SetN(1)
-						} else {
-							ies.aluResult.SetN(0)
-						}
-						fmt.Printf(" SLT   Rd=%02d  Rs1v=0x%08X  Rs2v=0x%08X -> 0x%08X", decoded.rd, decoded.rs1V, decoded.rs2V, ies.aluResult.GetN())
-					} else {
-						if int32(decoded.rs1V) < imm32 {
-							ies.aluResult.SetN(1)
-						} else {
-							ies.aluResult.SetN(0)
-						}
-						fmt.Printf(" SLTI  Rd=%02d  Rs1v=0x%08X  imm=0x%08X -> 0x%08X", decoded.rd, decoded.rs1V, imm32, ies.aluResult.GetN())
-					}
+				ies.tracer.Printf(" DIVU   Rd=%02d  Rs1v=0x%08X  Rs2v=0x%08X -> 0x%08X", decoded.rd, rs1V, rs2V, ies.aluResult.GetN())
+			case TagRem:
+				dividend, divisor := int32(rs1V), int32(rs2V)
+				switch {
+				case divisor == 0:
+					ies.aluResult.SetN(uint32(dividend))
+				case dividend == int32(-2147483648) && divisor == -1:
+					ies.aluResult.SetN(0)
+				default:
+					ies.aluResult.SetN(uint32(dividend % divisor))
 				}
-			case OP_SLTU:
-				{
-					if isRegisterOp {
-						if decoded.rs1V < decoded.rs2V {
-							ies.aluResult.SetN(1)
-						} else {
-							ies.aluResult.SetN(0)
-						}
-						fmt.Printf(" SLTU  Rd=%02d  Rs1v=0x%08X  Rs2v=0x%08X -> 0x%08X", decoded.rd, decoded.rs1V, decoded.rs2V, ies.aluResult.GetN())
-					} else {
-						if decoded.rs1V < uint32(imm32) {
-							ies.aluResult.SetN(1)
-						} else {
-							ies.aluResult.SetN(0)
-						}
-						fmt.Printf(" SLTIU Rd=%02d  Rs1v=0x%08X  imm=0x%08X -> 0x%08X", decoded.rd, decoded.rs1V, imm32, ies.aluResult.GetN())
-					}
+				ies.tracer.Printf(" REM    Rd=%02d  Rs1v=0x%08X  Rs2v=0x%08X -> 0x%08X", decoded.rd, rs1V, rs2V, ies.aluResult.GetN())
+			case TagRemu:
+				if rs2V == 0 {
+					ies.aluResult.SetN(rs1V)
+				} else {
+					ies.aluResult.SetN(rs1V % rs2V)
+				}
+				ies.tracer.Printf(" REMU   Rd=%02d  Rs1v=0x%08X  Rs2v=0x%08X -> 0x%08X", decoded.rd, rs1V, rs2V, ies.aluResult.GetN())
+			}
+		} else if decoded.isFusedOp {
+			// Synthesized by FuserStage: these tags never come from
+			// LookupDesc, only from a recognized two-instruction pattern.
+			switch decoded.fusedTag {
+			case TagLoadImm32:
+				ies.aluResult.SetN(uint32(imm32))
+				ies.tracer.Printf(" LOAD_IMM32 Rd=%02d  imm32=0x%08X -> 0x%08X", decoded.rd, imm32, ies.aluResult.GetN())
+			case TagLoadPcRel:
+				ies.aluResult.SetN(uint32(int32(decoded.pc) + imm32))
+				ies.tracer.Printf(" LOAD_PC_REL Rd=%02d  pc=0x%08X  off=0x%08X -> 0x%08X", decoded.rd, decoded.pc, imm32, ies.aluResult.GetN())
+			case TagLoadPcRelMem:
+				ies.aluResult.SetN(uint32(int32(decoded.pc) + imm32))
+				ies.tracer.Printf(" LOAD_PC_REL_MEM Rd=%02d  addr=0x%08X", decoded.rd, ies.aluResult.GetN())
+			case TagZext:
+				mask := uint32(1)<<uint(imm32) - 1
+				ies.aluResult.SetN(rs1V & mask)
+				ies.tracer.Printf(" ZEXT.%d Rd=%02d  Rs1v=0x%08X -> 0x%08X", imm32, decoded.rd, rs1V, ies.aluResult.GetN())
+			case TagIndexedLoad:
+				ies.aluResult.SetN(rs1V + rs2V)
+				ies.tracer.Printf(" INDEXED_LOAD Rd=%02d  addr=0x%08X", decoded.rd, ies.aluResult.GetN())
+			}
+		} else if decoded.isAluOp && descFound {
+
+			// Dispatch on the InstrDesc's SemanticTag rather than raw
+			// func3/funct7 bits: a new ALU instruction plugs in by
+			// registering a table entry in instr_desc.go, not by adding a
+			// case here. isRegisterOp still distinguishes the handful of
+			// tags ADD/ADDI etc. share between their R-type and I-type
+			// forms; TagAluSub and TagAluSra don't need it, since SUB has
+			// no immediate form and SRAI has no register form.
+			switch desc.Tag {
+			case TagAluAdd:
+				if isRegisterOp {
+					ies.aluResult.SetN(rs1V + rs2V)
+					ies.tracer.Printf(" ADD   Rd=%02d  Rs1v=0x%08X  Rs2v=0x%08X -> 0x%08X", decoded.rd, rs1V, rs2V, ies.aluResult.GetN())
+				} else {
+					ies.aluResult.SetN(rs1V + uint32(imm32))
+					ies.tracer.Printf(" ADDI  Rd=%02d  Rs1v=0x%08X  imm=0x%08X -> 0x%08X", decoded.rd, rs1V, imm32, ies.aluResult.GetN())
 				}
-			case OP_XOR:
-				{
-					if isRegisterOp {
-						ies.aluResult.SetN(decoded.rs1V ^ decoded.rs2V)
-						fmt.Printf(" XOR   Rd=%02d  Rs1v=0x%08X  Rs2v=0x%08X -> 0x%08X", decoded.rd, decoded.rs1V, decoded.rs2V, ies.aluResult.GetN())
+			case TagAluSub:
+				ies.aluResult.SetN(rs1V - rs2V)
+				ies.tracer.Printf(" SUB   Rd=%02d  Rs1v=0x%08X  Rs2v=0x%08X -> 0x%08X", decoded.rd, rs1V, rs2V, ies.aluResult.GetN())
+			case TagAluSll:
+				if isRegisterOp {
+					shiftAmount := rs2V & 0x1F
+					ies.aluResult.SetN(rs1V << shiftAmount)
+					ies.shiftAmount.SetN(byte(shiftAmount))
+					ies.tracer.Printf(" SLL   Rd=%02d  Rs1v=0x%08X  Rs2v=0x%08X -> 0x%08X", decoded.rd, rs1V, rs2V, ies.aluResult.GetN())
+				} else {
+					shiftAmount := decoded.shamt & 0x1F
+					ies.aluResult.SetN(rs1V << shiftAmount)
+					ies.shiftAmount.SetN(byte(shiftAmount))
+					ies.tracer.Printf(" SLLI  Rd=%02d  Rs1v=0x%08X  sha=0x%08X -> 0x%08X", decoded.rd, rs1V, shiftAmount, ies.aluResult.GetN())
+				}
+			case TagAluSlt:
+				if isRegisterOp {
+					if int32(rs1V) < int32(rs2V) {
+						ies.aluResult.SetN(1)
 					} else {
-						ies.aluResult.SetN(decoded.rs1V ^ uint32(imm32))
-						fmt.Printf(" XORI  Rd=%02d  Rs1v=0x%08X  imm=0x%08X -> 0x%08X", decoded.rd, decoded.rs1V, imm32, ies.aluResult.GetN())
+						ies.aluResult.SetN(0)
 					}
-				}
-			case OP_SRL:
-				{
-					if isRegisterOp {
-						shiftAmount := decoded.rs2V & 0x1F
-						ies.aluResult.SetN(decoded.rs1V >> shiftAmount)
-						fmt.Printf(" SRL   Rd=%02d  Rs1v=0x%08X  Rs2v=0x%08X -> 0x%08X", decoded.rd, decoded.rs1V, decoded.rs2V, ies.aluResult.GetN())
+					ies.tracer.Printf(" SLT   Rd=%02d  Rs1v=0x%08X  Rs2v=0x%08X -> 0x%08X", decoded.rd, rs1V, rs2V, ies.aluResult.GetN())
+				} else {
+					if int32(rs1V) < imm32 {
+						ies.aluResult.SetN(1)
 					} else {
-						shiftAmount := decoded.shamt & 0x1F
-						ies.aluResult.SetN(decoded.rs1V >> shiftAmount)
-						fmt.Printf(" SRLI  Rd=%02d  Rs1v=0x%08X  sha=0x%08X -> 0x%08X", decoded.rd, decoded.rs1V, shiftAmount, ies.aluResult.GetN())
+						ies.aluResult.SetN(0)
 					}
+					ies.tracer.Printf(" SLTI  Rd=%02d  Rs1v=0x%08X  imm=0x%08X -> 0x%08X", decoded.rd, rs1V, imm32, ies.aluResult.GetN())
 				}
-			case OP_OR:
-				{
-					if isRegisterOp {
-						ies.aluResult.SetN(decoded.rs1V | decoded.rs2V)
-						fmt.Printf(" OR    Rd=%02d  Rs1v=0x%08X  Rs2v=0x%08X -> 0x%08X", decoded.rd, decoded.rs1V, decoded.rs2V, ies.aluResult.GetN())
+			case TagAluSltu:
+				if isRegisterOp {
+					if rs1V < rs2V {
+						ies.aluResult.SetN(1)
 					} else {
-						ies.aluResult.SetN(decoded.rs1V | uint32(imm32))
-						fmt.Printf(" ORI   Rd=%02d  Rs1v=0x%08X  imm=0x%08X -> 0x%08X", decoded.rd, decoded.rs1V, imm32, ies.aluResult.GetN())
+						ies.aluResult.SetN(0)
 					}
-				}
-			case OP_AND:
-				{
-					if isRegisterOp {
-						ies.aluResult.SetN(decoded.rs1V & decoded.rs2V)
-						fmt.Printf(" AND   Rd=%02d  Rs1v=0x%08X  Rs2v=0x%08X -> 0x%08X", decoded.rd, decoded.rs1V, decoded.rs2V, ies.aluResult.GetN())
+					ies.tracer.Printf(" SLTU  Rd=%02d  Rs1v=0x%08X  Rs2v=0x%08X -> 0x%08X", decoded.rd, rs1V, rs2V, ies.aluResult.GetN())
+				} else {
+					if rs1V < uint32(imm32) {
+						ies.aluResult.SetN(1)
 					} else {
-						ies.aluResult.SetN(decoded.rs1V & uint32(imm32))
-						fmt.Printf(" ANDI  Rd=%02d  Rs1v=0x%08X  imm=0x%08X -> 0x%08X", decoded.rd, decoded.rs1V, imm32, ies.aluResult.GetN())
+						ies.aluResult.SetN(0)
 					}
+					ies.tracer.Printf(" SLTIU Rd=%02d  Rs1v=0x%08X  imm=0x%08X -> 0x%08X", decoded.rd, rs1V, imm32, ies.aluResult.GetN())
+				}
+			case TagAluXor:
+				if isRegisterOp {
+					ies.aluResult.SetN(rs1V ^ rs2V)
+					ies.tracer.Printf(" XOR   Rd=%02d  Rs1v=0x%08X  Rs2v=0x%08X -> 0x%08X", decoded.rd, rs1V, rs2V, ies.aluResult.GetN())
+				} else {
+					ies.aluResult.SetN(rs1V ^ uint32(imm32))
+					ies.tracer.Printf(" XORI  Rd=%02d  Rs1v=0x%08X  imm=0x%08X -> 0x%08X", decoded.rd, rs1V, imm32, ies.aluResult.GetN())
+				}
+			case TagAluSrl:
+				shiftAmount := rs2V & 0x1F
+				if !isRegisterOp {
+					shiftAmount = uint32(decoded.shamt) & 0x1F
+				}
+				ies.aluResult.SetN(rs1V >> shiftAmount)
+				ies.shiftAmount.SetN(byte(shiftAmount))
+				if isRegisterOp {
+					ies.tracer.Printf(" SRL   Rd=%02d  Rs1v=0x%08X  Rs2v=0x%08X -> 0x%08X", decoded.rd, rs1V, rs2V, ies.aluResult.GetN())
+				} else {
+					ies.tracer.Printf(" SRLI  Rd=%02d  Rs1v=0x%08X  sha=0x%08X -> 0x%08X", decoded.rd, rs1V, shiftAmount, ies.aluResult.GetN())
+				}
+			case TagAluSra:
+				shiftAmount := decoded.shamt & 0x1F
+				ies.aluResult.SetN(uint32(int32(rs1V) >> shiftAmount))
+				ies.shiftAmount.SetN(byte(shiftAmount))
+				ies.tracer.Printf(" SRAI  Rd=%02d  Rs1v=0x%08X  sha=0x%08X -> 0x%08X", decoded.rd, rs1V, shiftAmount, ies.aluResult.GetN())
+			case TagAluOr:
+				if isRegisterOp {
+					ies.aluResult.SetN(rs1V | rs2V)
+					ies.tracer.Printf(" OR    Rd=%02d  Rs1v=0x%08X  Rs2v=0x%08X -> 0x%08X", decoded.rd, rs1V, rs2V, ies.aluResult.GetN())
+				} else {
+					ies.aluResult.SetN(rs1V | uint32(imm32))
+					ies.tracer.Printf(" ORI   Rd=%02d  Rs1v=0x%08X  imm=0x%08X -> 0x%08X", decoded.rd, rs1V, imm32, ies.aluResult.GetN())
+				}
+			case TagAluAnd:
+				if isRegisterOp {
+					ies.aluResult.SetN(rs1V & rs2V)
+					ies.tracer.Printf(" AND   Rd=%02d  Rs1v=0x%08X  Rs2v=0x%08X -> 0x%08X", decoded.rd, rs1V, rs2V, ies.aluResult.GetN())
+				} else {
+					ies.aluResult.SetN(rs1V & uint32(imm32))
+					ies.tracer.Printf(" ANDI  Rd=%02d  Rs1v=0x%08X  imm=0x%08X -> 0x%08X", decoded.rd, rs1V, imm32, ies.aluResult.GetN())
 				}
 			}
 		}
+
+		ies.tracer.OnExecute(ies.GetExecutionValuesOut())
 	}
 }
 
 func (ies *ExecuteStage) LatchNext() {
 	ies.aluResult.LatchNext()
+	ies.insn.LatchNext()
 	ies.rd.LatchNext()
+	ies.rs1Addr.LatchNext()
+	ies.rs2Addr.LatchNext()
 	ies.rs1V.LatchNext()
 	ies.rs2V.LatchNext()
+	ies.pc.LatchNext()
 
 	ies.isAluOp.LatchNext()
 	ies.isStoreOp.LatchNext()
 	ies.isLoadOp.LatchNext()
 	ies.isLuiOp.LatchNext()
 	ies.isJumpOp.LatchNext()
+	ies.isMulDivOp.LatchNext()
+	ies.isAmoOp.LatchNext()
+	ies.amoTag.LatchNext()
+	ies.isIllegalOp.LatchNext()
 
 	ies.pcPlus4.LatchNext()
+	ies.pcNext.LatchNext()
 	ies.branchAddress.LatchNext()
 	ies.branchValid.LatchNext()
 
 	ies.imm32.LatchNext()
 	ies.func3.LatchNext()
+	ies.shiftAmount.LatchNext()
+}
+
+// Serialize writes the EX latch registers that have a Serialize method
+// (the RUint32/RInt32/RByte ones; the RBool flags aren't snapshotted, and
+// Deserialize rederives them from the restored latches via
+// recomputeFlags). Order must match Deserialize.
+func (ies *ExecuteStage) Serialize(w io.Writer) error {
+	if err := ies.aluResult.Serialize(w); err != nil {
+		return err
+	}
+	if err := ies.insn.Serialize(w); err != nil {
+		return err
+	}
+	if err := ies.rd.Serialize(w); err != nil {
+		return err
+	}
+	if err := ies.rs1Addr.Serialize(w); err != nil {
+		return err
+	}
+	if err := ies.rs2Addr.Serialize(w); err != nil {
+		return err
+	}
+	if err := ies.rs1V.Serialize(w); err != nil {
+		return err
+	}
+	if err := ies.rs2V.Serialize(w); err != nil {
+		return err
+	}
+	if err := ies.pc.Serialize(w); err != nil {
+		return err
+	}
+	if err := ies.amoTag.Serialize(w); err != nil {
+		return err
+	}
+	if err := ies.pcPlus4.Serialize(w); err != nil {
+		return err
+	}
+	if err := ies.pcNext.Serialize(w); err != nil {
+		return err
+	}
+	if err := ies.branchAddress.Serialize(w); err != nil {
+		return err
+	}
+	if err := ies.imm32.Serialize(w); err != nil {
+		return err
+	}
+	if err := ies.func3.Serialize(w); err != nil {
+		return err
+	}
+	return ies.shiftAmount.Serialize(w)
+}
+
+// Deserialize restores the EX latch registers written by Serialize.
+func (ies *ExecuteStage) Deserialize(r io.Reader) error {
+	if err := ies.aluResult.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ies.insn.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ies.rd.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ies.rs1Addr.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ies.rs2Addr.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ies.rs1V.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ies.rs2V.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ies.pc.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ies.amoTag.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ies.pcPlus4.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ies.pcNext.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ies.branchAddress.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ies.imm32.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ies.func3.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ies.shiftAmount.Deserialize(r); err != nil {
+		return err
+	}
+	ies.recomputeFlags()
+	return nil
+}
+
+// recomputeFlags rederives the RBool flags Serialize skips (isAluOp,
+// isLoadOp, branchValid, etc.) from insn/func3/imm32/rs1V/rs2V Deserialize
+// just restored, the same mapping Compute uses when it first computes the
+// instruction now latched in EXECUTE. A restored mid-pipeline snapshot has
+// no upcoming Compute call to do this, the same reason DecodeStage's
+// Deserialize needs its own recomputeFlags.
+func (ies *ExecuteStage) recomputeFlags() {
+	insn := ies.insn.Get()
+	opcode := byte(insn & 0x7F)
+	func7 := byte((insn >> 25) & 0x7F)
+	func3 := ies.func3.Get()
+	imm32 := ies.imm32.Get()
+
+	isAluOp := opcode&0b1011111 == 0b0010011
+	isStoreOp := opcode == 0b0100011
+	isLoadOp := opcode == 0b0000011
+	isLuiOp := opcode == 0b0110111
+	isJumpOp := opcode == JAL_OPCODE || opcode == JALR_OPCODE
+	isBranchOp := opcode == BRANCH_OPCODE
+	isMulDivOp := opcode == REGISTER_OPCODE && func7 == FUNC7_MULDIV
+	isAmoOp := opcode == AMO_OPCODE
+
+	set := func(r *RBool, v bool) {
+		r.SetN(v)
+		r.LatchNext()
+	}
+
+	set(&ies.isAluOp, isAluOp)
+	set(&ies.isStoreOp, isStoreOp)
+	set(&ies.isLoadOp, isLoadOp)
+	set(&ies.isLuiOp, isLuiOp)
+	set(&ies.isJumpOp, isJumpOp)
+	set(&ies.isMulDivOp, isMulDivOp)
+	set(&ies.isAmoOp, isAmoOp)
+
+	_, descFound := LookupDesc(opcode, func3, func7, imm32)
+	recognized := isAluOp || isStoreOp || isLoadOp || isLuiOp || isJumpOp ||
+		isBranchOp || isMulDivOp || isAmoOp ||
+		opcode == SYSTEM_OPCODE || opcode == FENCE_OPCODE ||
+		opcode == 0b0010111 // AUIPC, which EX has no flag of its own for
+	set(&ies.isIllegalOp, !recognized || (isAluOp && !descFound))
+
+	branchConditionMet := false
+	if isBranchOp {
+		rs1V := ies.rs1V.Get()
+		rs2V := ies.rs2V.Get()
+		switch func3 {
+		case FUNC3_BEQ:
+			branchConditionMet = rs1V == rs2V
+		case FUNC3_BNE:
+			branchConditionMet = rs1V != rs2V
+		case FUNC3_BLT:
+			branchConditionMet = int32(rs1V) < int32(rs2V)
+		case FUNC3_BGE:
+			branchConditionMet = int32(rs1V) >= int32(rs2V)
+		case FUNC3_BLTU:
+			branchConditionMet = rs1V < rs2V
+		case FUNC3_BGEU:
+			branchConditionMet = rs1V >= rs2V
+		}
+	}
+	set(&ies.branchValid, isJumpOp || (isBranchOp && branchConditionMet))
 }
 
 type ExecutedValues struct {
-	isAluOp   bool
-	isStoreOp bool
-	isLoadOp  bool
-	isLuiOp   bool
-	isJumpOp  bool
+	isAluOp     bool
+	isStoreOp   bool
+	isLoadOp    bool
+	isLuiOp     bool
+	isJumpOp    bool
+	isMulDivOp  bool
+	isAmoOp     bool
+	amoTag      SemanticTag
+	isIllegalOp bool
 
 	writeBackValue uint32
+	insn           uint32
 	rd             byte
+	rs1Addr        byte
+	rs2Addr        byte
 	rs1V           uint32
 	rs2V           uint32
+	pc             uint32
 
 	imm32 int32
 	func3 byte
 
 	pcPlus4       uint32
+	pcNext        uint32
 	BranchAddress uint32
 	BranchValid   bool
 }
 
+// GetExecutionValuesOut returns EX's latched output: what EX finished
+// executing as of the end of the previous cycle, the value MA consumes as
+// its input this cycle (and IF consumes for branch redirection). MA's
+// same-cycle, still-in-flight result is exposed separately by MA's
+// GetForwardOut, for EX's bypass path (see SetForwardSource).
 func (ies *ExecuteStage) GetExecutionValuesOut() ExecutedValues {
 	return ExecutedValues{
-		isAluOp:   ies.isAluOp.GetN(),
-		isStoreOp: ies.isStoreOp.GetN(),
-		isLoadOp:  ies.isLoadOp.GetN(),
-		isLuiOp:   ies.isLuiOp.GetN(),
-		isJumpOp:  ies.isJumpOp.GetN(),
+		isAluOp:     ies.isAluOp.Get(),
+		isStoreOp:   ies.isStoreOp.Get(),
+		isLoadOp:    ies.isLoadOp.Get(),
+		isLuiOp:     ies.isLuiOp.Get(),
+		isJumpOp:    ies.isJumpOp.Get(),
+		isMulDivOp:  ies.isMulDivOp.Get(),
+		isAmoOp:     ies.isAmoOp.Get(),
+		amoTag:      SemanticTag(ies.amoTag.Get()),
+		isIllegalOp: ies.isIllegalOp.Get(),
+
+		writeBackValue: ies.aluResult.Get(),
+		insn:           ies.insn.Get(),
+		rd:             ies.rd.Get(),
+		rs1Addr:        ies.rs1Addr.Get(),
+		rs2Addr:        ies.rs2Addr.Get(),
+		rs1V:           ies.rs1V.Get(),
+		rs2V:           ies.rs2V.Get(),
+		pc:             ies.pc.Get(),
+
+		imm32: ies.imm32.Get(),
+		func3: ies.func3.Get(),
+
+		pcPlus4:       ies.pcPlus4.Get(),
+		pcNext:        ies.pcNext.Get(),
+		BranchAddress: ies.branchAddress.Get(),
+		BranchValid:   ies.branchValid.Get(),
+	}
+}
 
-		writeBackValue: ies.aluResult.GetN(),
-		rd:             ies.rd.GetN(),
-		rs1V:           ies.rs1V.GetN(),
-		rs2V:           ies.rs2V.GetN(),
+// PcPlus4 exposes ExecutedValues.pcPlus4 to callers outside package
+// pipeline, such as cpu.go's handleMemFault.
+func (ev ExecutedValues) PcPlus4() uint32 { return ev.pcPlus4 }
 
-		imm32: ies.imm32.GetN(),
-		func3: ies.func3.GetN(),
+// Insn exposes ExecutedValues.insn to callers outside package pipeline,
+// such as cpu.go's handleIllegalInstruction/handleIllegalMemOp, for the
+// mtval recorded on an illegal-instruction trap.
+func (ev ExecutedValues) Insn() uint32 { return ev.insn }
 
-		pcPlus4:       ies.pcPlus4.GetN(),
-		BranchAddress: ies.branchAddress.GetN(),
-		BranchValid:   ies.branchValid.GetN(),
-	}
-}
+// IsIllegalOp reports an opcode DecodeStage couldn't classify, or an ALU
+// encoding LookupDesc couldn't resolve, so cpu.go can route it through a CSR
+// illegal-instruction trap instead of letting aluResult commit a stale
+// value.
+func (ev ExecutedValues) IsIllegalOp() bool { return ev.isIllegalOp }
@@ -2,6 +2,7 @@ package pipeline
 
 import (
 	"fmt"
+	"io"
 	. "riscv/system_interface"
 )
 
@@ -38,8 +39,44 @@ type InstructionFetchStage struct {
 
 	bus         *SystemInterface
 	shouldStall func() bool
+	tracer      Tracer
+
+	fetchIssued  bool   // a bus transaction for the current pc is in flight
+	stalled      bool   // that transaction hasn't reported ready yet
+	fetchLowDone bool   // the low halfword at pc has been read for this fetch
+	fetchLow     uint16 // that halfword, pending the quadrant check below
+
+	// illegalFetch/illegalFetchPC report a fetch this Compute call couldn't
+	// resolve into a valid instruction (a bus error reading either halfword,
+	// or a compressed encoding Decompress doesn't recognize), for
+	// RVI32System to turn into a CSR trap instead of crashing the process.
+	// Plain fields, reset every Compute call, not RBool/RByte latches: the
+	// same "consumed the same cycle it's raised" convention MemoryAccessStage
+	// uses for memFault.
+	illegalFetch   bool
+	illegalFetchPC uint32
+
+	breakpoints map[uint32]bool
+	debugger    Debugger
 }
 
+// Debugger receives control when a fetch-stage breakpoint fires, the same
+// hand-off the PSX debug facility makes to an attached debugger once BPC
+// matches. OnBreak is called synchronously from Compute, once per fetch at
+// a breakpointed pc; it does not itself stop the pipeline (this core has
+// no separate "debugger" thread to hand control to) but records the hit
+// for whatever is driving Cycle() in a loop (e.g. GDBStub's continue/step
+// handling) to notice and stop calling Cycle() afterward.
+type Debugger interface {
+	OnBreak(pc uint32)
+}
+
+// NopDebugger never stops. It is the default so IF can always call into a
+// debugger without a nil check.
+type NopDebugger struct{}
+
+func (NopDebugger) OnBreak(pc uint32) {}
+
 func NewInstructionFetchStage(params *InstructionFetchParams) *InstructionFetchStage {
 	ifs := &InstructionFetchStage{}
 
@@ -51,9 +88,44 @@ func NewInstructionFetchStage(params *InstructionFetchParams) *InstructionFetchS
 	ifs.getBranchAddress = params.getBranchAddress
 	ifs.getBranchAddressValid = params.getBranchAddressValid
 	ifs.shouldStall = params.shouldStall
+	ifs.tracer = NopTracer{}
+	ifs.debugger = NopDebugger{}
 	return ifs
 }
 
+// SetTracer attaches a Tracer to receive this stage's trace output. Pass
+// nil to go back to discarding it.
+func (ifs *InstructionFetchStage) SetTracer(tracer Tracer) {
+	if tracer == nil {
+		tracer = NopTracer{}
+	}
+	ifs.tracer = tracer
+}
+
+// SetDebugger attaches a Debugger to receive control when a PC breakpoint
+// fires. Pass nil to go back to never stopping.
+func (ifs *InstructionFetchStage) SetDebugger(debugger Debugger) {
+	if debugger == nil {
+		debugger = NopDebugger{}
+	}
+	ifs.debugger = debugger
+}
+
+// AddBreakpoint arms a software breakpoint at pc: the next fetch that
+// issues a transaction for pc hands control to the attached Debugger
+// before the instruction there commits.
+func (ifs *InstructionFetchStage) AddBreakpoint(pc uint32) {
+	if ifs.breakpoints == nil {
+		ifs.breakpoints = map[uint32]bool{}
+	}
+	ifs.breakpoints[pc] = true
+}
+
+// RemoveBreakpoint disarms a breakpoint previously set with AddBreakpoint.
+func (ifs *InstructionFetchStage) RemoveBreakpoint(pc uint32) {
+	delete(ifs.breakpoints, pc)
+}
+
 func (ifs *InstructionFetchStage) readyToSend() bool {
 	return true
 }
@@ -63,28 +135,126 @@ func (ifs *InstructionFetchStage) readyToReceive() bool {
 }
 
 func (ifs *InstructionFetchStage) Compute() {
-	if !ifs.shouldStall() {
+	if ifs.shouldStall() {
+		return
+	}
+
+	ifs.illegalFetch = false
 
+	if !ifs.fetchIssued {
 		if ifs.getBranchAddressValid() {
 			ifs.pc.SetN(ifs.getBranchAddress())
-			fmt.Println()
+			ifs.tracer.Printf("\n")
+			ifs.tracer.OnFlush(fmt.Sprintf("redirect to 0x%08X", ifs.getBranchAddress()))
 		} else {
 			ifs.pc.SetN(ifs.pcPlus4.GetN())
 		}
 
-		// fmt.Println("@ INSTRUCTION_FETCH")
+		ifs.fetchIssued = true
+		ifs.fetchLowDone = false
 
-		ifs.pcPlus4.SetN(ifs.pc.GetN() + 4)
+		if ifs.breakpoints[ifs.pc.GetN()] {
+			ifs.debugger.OnBreak(ifs.pc.GetN())
+		}
+	}
 
-		ins, err := ifs.bus.Read(ifs.pc.GetN(), MEMORY_WIDTH_WORD)
+	// Every fetch starts with the halfword at pc: bits [1:0] say whether
+	// it's a compressed (RVC) instruction on its own, or the low half of
+	// an ordinary 32-bit one that needs a second halfword from pc+2.
+	//
+	// ROM_Device/RAM_Device's MEMORY_WIDTH_HALF extracts offset 0 within a
+	// word as bits[31:16] and offset 2 as bits[15:0] (see rom.go/ram.go) —
+	// the reverse of the little-endian halfword order RVC quadrant
+	// detection and DecodeStage's bit layout assume. So the architectural
+	// low halfword of the instruction at pc comes off the bus at pc+2,
+	// and the high halfword at pc itself.
+	if !ifs.fetchLowDone {
+		low, ready, err := ifs.bus.Read(ifs.pc.GetN()+2, MEMORY_WIDTH_HALF)
 		if err != nil {
-			panic(err)
+			ifs.illegalFetch = true
+			ifs.illegalFetchPC = ifs.pc.GetN()
+			ifs.stalled = false
+			ifs.fetchIssued = false
+			return
+		}
+		if !ready {
+			ifs.stalled = true
+			return
+		}
+		ifs.fetchLow = uint16(low)
+		ifs.fetchLowDone = true
+	}
+
+	// The all-zero end-of-program sentinel has quadrant bits 0b00, which
+	// Decompress doesn't recognize (only quadrant-0 C.LW, funct3 0b010, is
+	// implemented) and would otherwise fault illegal before IsEndOfProgram
+	// ever gets a chance to halt the pipeline. Let it fall through to the
+	// ordinary 32-bit path instead, which reads the other all-zero
+	// halfword too and leaves instruction at 0 for IsEndOfProgram to see.
+	if ifs.fetchLow&0b11 != 0b11 && ifs.fetchLow != 0 {
+		expanded, ok := Decompress(ifs.fetchLow)
+		if !ok {
+			ifs.illegalFetch = true
+			ifs.illegalFetchPC = ifs.pc.GetN()
+			ifs.stalled = false
+			ifs.fetchIssued = false
+			return
 		}
 
-		fmt.Printf("PC=0x%08X  INS=0x%08X ", ifs.pc.GetN(), ins)
+		ifs.stalled = false
+		ifs.fetchIssued = false
+		ifs.pcPlus4.SetN(ifs.pc.GetN() + 2)
 
-		ifs.instruction.SetN(ins)
+		ifs.tracer.Printf("PC=0x%08X  C.INS=0x%04X -> INS=0x%08X ", ifs.pc.GetN(), ifs.fetchLow, expanded)
+		ifs.tracer.OnFetch(ifs.pc.GetN(), expanded)
+		ifs.instruction.SetN(expanded)
+		return
+	}
+
+	high, ready, err := ifs.bus.Read(ifs.pc.GetN(), MEMORY_WIDTH_HALF)
+	if err != nil {
+		ifs.illegalFetch = true
+		ifs.illegalFetchPC = ifs.pc.GetN()
+		ifs.stalled = false
+		ifs.fetchIssued = false
+		return
+	}
+	if !ready {
+		ifs.stalled = true
+		return
 	}
+	ins := uint32(ifs.fetchLow) | uint32(high)<<16
+
+	ifs.stalled = false
+	ifs.fetchIssued = false
+	ifs.pcPlus4.SetN(ifs.pc.GetN() + 4)
+
+	ifs.tracer.Printf("PC=0x%08X  INS=0x%08X ", ifs.pc.GetN(), ins)
+	ifs.tracer.OnFetch(ifs.pc.GetN(), ins)
+	ifs.instruction.SetN(ins)
+}
+
+// IsStalled reports whether the in-flight fetch transaction is still
+// waiting on the bus, so RVI32System.Cycle can hold IF in INSTRUCTION_FETCH
+// instead of advancing to DECODE.
+func (ifs *InstructionFetchStage) IsStalled() bool {
+	return ifs.stalled
+}
+
+// IsEndOfProgram reports whether the word IF just finished fetching this
+// cycle is the all-zero terminator, so RVI32System can halt the pipeline
+// without waiting for it to cross the DE latch (GetFetchValuesOut only
+// exposes last cycle's word, one cycle too late for this check).
+func (ifs *InstructionFetchStage) IsEndOfProgram() bool {
+	return ifs.instruction.GetN() == 0
+}
+
+// IllegalFetch reports a fetch this Compute call couldn't resolve into a
+// valid instruction (a bus error reading either halfword, or a compressed
+// encoding Decompress doesn't recognize), so RVI32System can turn it into a
+// CSR illegal-instruction trap instead of crashing the process.
+func (ifs *InstructionFetchStage) IllegalFetch() (pc uint32, ok bool) {
+	return ifs.illegalFetchPC, ifs.illegalFetch
 }
 
 func (ifs *InstructionFetchStage) LatchNext() {
@@ -93,16 +263,46 @@ func (ifs *InstructionFetchStage) LatchNext() {
 	ifs.pcPlus4.LatchNext()
 }
 
+// Serialize writes the IF latch registers (pc, pcPlus4, instruction) as
+// part of a full machine snapshot.
+func (ifs *InstructionFetchStage) Serialize(w io.Writer) error {
+	if err := ifs.pc.Serialize(w); err != nil {
+		return err
+	}
+	if err := ifs.pcPlus4.Serialize(w); err != nil {
+		return err
+	}
+	return ifs.instruction.Serialize(w)
+}
+
+// Deserialize restores the IF latch registers written by Serialize.
+func (ifs *InstructionFetchStage) Deserialize(r io.Reader) error {
+	if err := ifs.pc.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ifs.pcPlus4.Deserialize(r); err != nil {
+		return err
+	}
+	return ifs.instruction.Deserialize(r)
+}
+
 type FetchValues struct {
 	Instruction uint32
 	pc          uint32
 	pcPlus4     uint32
 }
 
+// GetFetchValuesOut returns IF's latched output: the instruction it
+// finished fetching as of the end of the previous cycle, the value DE
+// consumes as its input this cycle.
 func (ifs *InstructionFetchStage) GetFetchValuesOut() FetchValues {
 	return FetchValues{
-		Instruction: ifs.instruction.GetN(),
-		pc:          ifs.pc.GetN(),
-		pcPlus4:     ifs.pcPlus4.GetN(),
+		Instruction: ifs.instruction.Get(),
+		pc:          ifs.pc.Get(),
+		pcPlus4:     ifs.pcPlus4.Get(),
 	}
 }
+
+// Pc exposes FetchValues.pc to callers outside package pipeline, such as
+// cpu.go's checkInterrupts.
+func (fv FetchValues) Pc() uint32 { return fv.pc }
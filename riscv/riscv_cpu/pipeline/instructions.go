@@ -8,6 +8,67 @@ const LOAD_OPCODE = 0b0000011
 const STORE_OPCODE = 0b0100011
 const JAL_OPCODE = 0b1101111
 const JALR_OPCODE = 0b1100111
+const SYSTEM_OPCODE = 0b1110011
+const AMO_OPCODE = 0b0101111
+const FENCE_OPCODE = 0b0001111
+const BRANCH_OPCODE = 0b1100011
+
+// BRANCH_OPCODE funct3
+const (
+	FUNC3_BEQ  = 0b000
+	FUNC3_BNE  = 0b001
+	FUNC3_BLT  = 0b100
+	FUNC3_BGE  = 0b101
+	FUNC3_BLTU = 0b110
+	FUNC3_BGEU = 0b111
+)
+
+// SYSTEM opcode funct3: CSR ops, or 0 for ECALL/EBREAK/MRET (distinguished
+// by imm[11:0]).
+const (
+	FUNC3_CSRRW  = 0b001
+	FUNC3_CSRRS  = 0b010
+	FUNC3_CSRRC  = 0b011
+	FUNC3_CSRRWI = 0b101
+	FUNC3_CSRRSI = 0b110
+	FUNC3_CSRRCI = 0b111
+
+	SYSTEM_IMM_ECALL  = 0x000
+	SYSTEM_IMM_EBREAK = 0x001
+	SYSTEM_IMM_MRET   = 0x302
+)
+
+// REGISTER_OPCODE funct7 for the M extension, and its funct3 sub-ops.
+const (
+	FUNC7_MULDIV = 0b0000001
+
+	FUNC3_MUL    = 0b000
+	FUNC3_MULH   = 0b001
+	FUNC3_MULHSU = 0b010
+	FUNC3_MULHU  = 0b011
+	FUNC3_DIV    = 0b100
+	FUNC3_DIVU   = 0b101
+	FUNC3_REM    = 0b110
+	FUNC3_REMU   = 0b111
+)
+
+// AMO_OPCODE funct3 is always 0b010 (word-width ops); funct7 splits into
+// funct5 (bits [31:27]), aq (bit 26), rl (bit 25).
+const AMO_FUNC3 = 0b010
+
+const (
+	FUNCT5_AMOADD  = 0b00000
+	FUNCT5_AMOSWAP = 0b00001
+	FUNCT5_LR      = 0b00010
+	FUNCT5_SC      = 0b00011
+	FUNCT5_AMOXOR  = 0b00100
+	FUNCT5_AMOOR   = 0b01000
+	FUNCT5_AMOAND  = 0b01100
+	FUNCT5_AMOMIN  = 0b10000
+	FUNCT5_AMOMAX  = 0b10100
+	FUNCT5_AMOMINU = 0b11000
+	FUNCT5_AMOMAXU = 0b11100
+)
 
 func Bits(v uint32, lo, hi uint) uint32 {
 	return (v >> lo) & ((1 << (hi - lo + 1)) - 1)
@@ -56,12 +117,16 @@ type R_INS struct {
 }
 
 func (r R_INS) Encode() uint32 {
-	return uint32(r.Opcode) |
-		uint32(r.Rd)<<7 |
-		uint32(r.Funct3)<<12 |
-		uint32(r.Rs1)<<15 |
-		uint32(r.Rs2)<<20 |
-		uint32(r.Funct7)<<25
+	// Rs2 also carries shamt for the shift-immediate forms (SLLI/SRLI/
+	// SRAI), whose callers pass a raw, unmasked shift count — mask it to
+	// its 5-bit field so an out-of-range shamt wraps instead of bleeding
+	// into Funct7 and producing a different instruction entirely.
+	return uint32(r.Opcode)&0x7F |
+		(uint32(r.Rd)&0x1F)<<7 |
+		(uint32(r.Funct3)&0x7)<<12 |
+		(uint32(r.Rs1)&0x1F)<<15 |
+		(uint32(r.Rs2)&0x1F)<<20 |
+		(uint32(r.Funct7)&0x7F)<<25
 }
 
 func (r R_INS) String() string {
@@ -141,53 +206,105 @@ func (j J_INS) String() string {
 		j.Name, j.Rd, j.Imm)
 }
 
+type B_INS struct {
+	Name string
+	/*
+		RISC-V branches are always 2-byte aligned, so bit 0 is always 0.
+		Sign-extended 13-bit immediate to 32 bits.
+	*/
+	Imm    int32
+	Funct3 byte
+	Rs1    byte
+	Rs2    byte
+	Opcode byte
+}
+
+func (b B_INS) Encode() uint32 {
+	imm := uint32(b.Imm)
+	imm12 := (imm >> 12) & 0x1   // 1 bit
+	imm10_5 := (imm >> 5) & 0x3F // 6 bits
+	imm4_1 := (imm >> 1) & 0xF   // 4 bits
+	imm11 := (imm >> 11) & 0x1   // 1 bit
+	return uint32(b.Opcode) |
+		uint32(imm11)<<7 |
+		uint32(imm4_1)<<8 |
+		uint32(b.Funct3)<<12 |
+		uint32(b.Rs1)<<15 |
+		uint32(b.Rs2)<<20 |
+		uint32(imm10_5)<<25 |
+		uint32(imm12)<<31
+}
+
+func (b B_INS) String() string {
+	return fmt.Sprintf("B-Type: %s Rs1=x%d, Rs2=x%d, Imm=%d",
+		b.Name, b.Rs1, b.Rs2, b.Imm)
+}
+
+// Decode disassembles instr by looking up its InstrDesc in the same table
+// LookupDesc uses for dispatch, rather than re-deriving the opcode grouping
+// DecodeStage.Compute and ExecuteStage already classify separately: a
+// mnemonic only needs teaching to the table once to show up here too. Returns
+// nil for anything LookupDesc doesn't recognize (reserved encodings,
+// compressed instructions already expanded upstream by Decompress).
 func Decode(instr uint32) Instruction {
-	opcode := Bits(instr, 0, 6)
+	opcode := byte(Bits(instr, 0, 6))
+	func3 := byte(Bits(instr, 12, 14))
+	func7 := byte(Bits(instr, 25, 31))
+	systemImm := int32(Bits(instr, 20, 31))
+
+	desc, ok := LookupDesc(opcode, func3, func7, systemImm)
+	if !ok {
+		return nil
+	}
 
-	switch opcode {
+	switch desc.Format {
 
-	case 0x13, LOAD_OPCODE, JALR_OPCODE: // I-Type (e.g. ADDI, LW)
+	case FormatI: // e.g. ADDI, LW, JALR, ECALL, FENCE
 		return I_INS{
-			Opcode: uint8(opcode),
+			Name:   desc.Name,
+			Opcode: opcode,
 			Rd:     uint8(Bits(instr, 7, 11)),
-			Funct3: uint8(Bits(instr, 12, 14)),
+			Funct3: func3,
 			Rs1:    uint8(Bits(instr, 15, 19)),
 			Imm:    SignExtend(Bits(instr, 20, 31), 12),
 		}
 
-	case 0x33: // R-Type (e.g. ADD)
+	case FormatR: // e.g. ADD, LR.W, AMOSWAP.W
 		return R_INS{
-			Opcode: uint8(opcode),
+			Name:   desc.Name,
+			Opcode: opcode,
 			Rd:     uint8(Bits(instr, 7, 11)),
-			Funct3: uint8(Bits(instr, 12, 14)),
+			Funct3: func3,
 			Rs1:    uint8(Bits(instr, 15, 19)),
 			Rs2:    uint8(Bits(instr, 20, 24)),
-			Funct7: uint8(Bits(instr, 25, 31)),
+			Funct7: func7,
 		}
 
-	case 0x37, 0x17: // U-Type (LUI, AUIPC)
+	case FormatU: // LUI, AUIPC
 		imm := Bits(instr, 12, 31) << 12
 
 		return U_INS{
-			Opcode: uint8(opcode),
+			Name:   desc.Name,
+			Opcode: opcode,
 			Rd:     uint8(Bits(instr, 7, 11)),
 			Imm:    int32(imm),
 		}
 
-	case 0x23: // S-Type (SB, SH, SW)
+	case FormatS: // SB, SH, SW
 		imm :=
 			(Bits(instr, 25, 31) << 5) |
 				Bits(instr, 7, 11)
 
 		return S_INS{
-			Opcode: uint8(opcode),
-			Funct3: uint8(Bits(instr, 12, 14)),
+			Name:   desc.Name,
+			Opcode: opcode,
+			Funct3: func3,
 			Rs1:    uint8(Bits(instr, 15, 19)),
 			Rs2:    uint8(Bits(instr, 20, 24)),
 			Imm:    SignExtend(imm, 12),
 		}
 
-	case JAL_OPCODE: // J-Type (JAL)
+	case FormatJ: // JAL
 		imm :=
 			(Bits(instr, 31, 31) << 20) |
 				(Bits(instr, 21, 30) << 1) |
@@ -195,11 +312,27 @@ func Decode(instr uint32) Instruction {
 				(Bits(instr, 12, 19) << 12)
 
 		return J_INS{
-			Opcode: uint8(opcode),
+			Name:   desc.Name,
+			Opcode: opcode,
 			Rd:     uint8(Bits(instr, 7, 11)),
 			Imm:    SignExtend(imm, 21),
 		}
 
+	case FormatB: // BEQ, BNE, BLT, BGE, BLTU, BGEU
+		imm :=
+			(Bits(instr, 31, 31) << 12) |
+				(Bits(instr, 7, 7) << 11) |
+				(Bits(instr, 25, 30) << 5) |
+				(Bits(instr, 8, 11) << 1)
+
+		return B_INS{
+			Name:   desc.Name,
+			Opcode: opcode,
+			Funct3: func3,
+			Rs1:    uint8(Bits(instr, 15, 19)),
+			Rs2:    uint8(Bits(instr, 20, 24)),
+			Imm:    SignExtend(imm, 13),
+		}
 	}
 
 	return nil
@@ -294,25 +427,41 @@ func JType(name string, rd byte, imm int32, opcode byte) uint32 {
 	// 	uint32(opcode&0x7F)
 }
 
-// func JTypeDecode(instruction uint32) J_INS {
+// JTypeDecode is Decode's JAL_OPCODE case pulled out into its own
+// function: DecodeStage.Compute needs the J-type immediate before it
+// knows whether to also compute branchAddress, so it can't just call
+// Decode and take the J_INS out of the returned Instruction.
+func JTypeDecode(instruction uint32) J_INS {
+	imm20 := (int32(instruction) >> 31) & 0x1     // 1 bit
+	imm10_1 := (int32(instruction) >> 21) & 0x3FF // 10 bits
+	imm11 := (int32(instruction) >> 20) & 0x1     // 1 bit
+	imm19_12 := (int32(instruction) >> 12) & 0xFF // 8 bits
 
-// 	imm20 := (int32(instruction) >> 31) & 0x1     // 1 bit
-// 	imm10_1 := (int32(instruction) >> 21) & 0x3FF // 10 bits
-// 	imm11 := (int32(instruction) >> 20) & 0x1     // 1 bit
-// 	imm19_12 := (int32(instruction) >> 12) & 0xFF // 8 bits
+	// 21-bit immediate construction, implicitly with 0 as LSB
+	imm := (imm20 << 20) | (imm19_12 << 12) | (imm11 << 11) | (imm10_1 << 1)
 
-// 	// 21-bit immediate construction, implicitly with 0 as LSB
-// 	imm := (imm20 << 20) | (imm19_12 << 12) | (imm11 << 11) | (imm10_1 << 1)
+	// Sign-extend to 32 bits
+	imm = imm << 11 >> 11
 
-// 	// Sign-extend to 32 bits
-// 	imm = imm << 11 >> 11
+	return J_INS{
+		Imm:    imm,
+		Rd:     byte((instruction >> 7) & 0x1F),
+		Opcode: byte(instruction & 0x7F),
+	}
+}
 
-// 	return J_INS{
-// 		Imm:    imm,
-// 		Rd:     byte((instruction >> 7) & 0x1F),
-// 		Opcode: byte(instruction & 0x7F),
-// 	}
-// }
+// imm[12|10:5] rs2 rs1 funct3 imm[4:1|11] opcode
+func BType(name string, rs1 byte, rs2 byte, imm int32, funct3 byte, opcode byte) uint32 {
+	ins := B_INS{
+		Name:   name,
+		Opcode: opcode,
+		Funct3: funct3,
+		Rs1:    rs1,
+		Rs2:    rs2,
+		Imm:    imm,
+	}
+	return ins.Encode()
+}
 
 // imm[31:12] rd opcode
 func UType(name string, rd byte, imm int32, opcode byte) uint32 {
@@ -408,6 +557,12 @@ func SRLI(rd byte, rs1 byte, shamt byte) uint32 {
 	return RType("SRLI", rd, rs1, shamt, 0b0000000, 0b101, IMMEDIATE_OPCODE)
 }
 
+// x[rd] = int32(x[rs1]) >> shamt
+func SRAI(rd byte, rs1 byte, shamt byte) uint32 {
+	// 0100000 shamt rs1 101 rd 0010011
+	return RType("SRAI", rd, rs1, shamt, 0b0100000, 0b101, IMMEDIATE_OPCODE)
+}
+
 // x[rd] = x[rs1] | x[rs2]
 func OR(rd byte, rs1 byte, rs2 byte) uint32 {
 	// 0000000 rs2 rs1 110 rd 0110011
@@ -432,6 +587,138 @@ func ANDI(rd byte, rs1 byte, imm int32) uint32 {
 	return IType("ANDI", rd, rs1, imm, 0b111, IMMEDIATE_OPCODE)
 }
 
+// x[rd] = low 32 bits of (int32(x[rs1]) * int32(x[rs2]))
+func MUL(rd byte, rs1 byte, rs2 byte) uint32 {
+	// 0000001 rs2 rs1 000 rd 0110011
+	return RType("MUL", rd, rs1, rs2, FUNC7_MULDIV, FUNC3_MUL, REGISTER_OPCODE)
+}
+
+// x[rd] = high 32 bits of (int64(int32(x[rs1])) * int64(int32(x[rs2])))
+func MULH(rd byte, rs1 byte, rs2 byte) uint32 {
+	// 0000001 rs2 rs1 001 rd 0110011
+	return RType("MULH", rd, rs1, rs2, FUNC7_MULDIV, FUNC3_MULH, REGISTER_OPCODE)
+}
+
+// x[rd] = high 32 bits of (int64(int32(x[rs1])) * int64(uint32(x[rs2])))
+func MULHSU(rd byte, rs1 byte, rs2 byte) uint32 {
+	// 0000001 rs2 rs1 010 rd 0110011
+	return RType("MULHSU", rd, rs1, rs2, FUNC7_MULDIV, FUNC3_MULHSU, REGISTER_OPCODE)
+}
+
+// x[rd] = high 32 bits of (uint64(x[rs1]) * uint64(x[rs2]))
+func MULHU(rd byte, rs1 byte, rs2 byte) uint32 {
+	// 0000001 rs2 rs1 011 rd 0110011
+	return RType("MULHU", rd, rs1, rs2, FUNC7_MULDIV, FUNC3_MULHU, REGISTER_OPCODE)
+}
+
+// x[rd] = int32(x[rs1]) / int32(x[rs2]), with divide-by-zero -> -1 and
+// INT_MIN / -1 -> INT_MIN
+func DIV(rd byte, rs1 byte, rs2 byte) uint32 {
+	// 0000001 rs2 rs1 100 rd 0110011
+	return RType("DIV", rd, rs1, rs2, FUNC7_MULDIV, FUNC3_DIV, REGISTER_OPCODE)
+}
+
+// x[rd] = uint32(x[rs1]) / uint32(x[rs2]), with divide-by-zero -> 2^32-1
+func DIVU(rd byte, rs1 byte, rs2 byte) uint32 {
+	// 0000001 rs2 rs1 101 rd 0110011
+	return RType("DIVU", rd, rs1, rs2, FUNC7_MULDIV, FUNC3_DIVU, REGISTER_OPCODE)
+}
+
+// x[rd] = int32(x[rs1]) % int32(x[rs2]), with divide-by-zero -> x[rs1] and
+// INT_MIN % -1 -> 0
+func REM(rd byte, rs1 byte, rs2 byte) uint32 {
+	// 0000001 rs2 rs1 110 rd 0110011
+	return RType("REM", rd, rs1, rs2, FUNC7_MULDIV, FUNC3_REM, REGISTER_OPCODE)
+}
+
+// x[rd] = uint32(x[rs1]) % uint32(x[rs2]), with divide-by-zero -> x[rs1]
+func REMU(rd byte, rs1 byte, rs2 byte) uint32 {
+	// 0000001 rs2 rs1 111 rd 0110011
+	return RType("REMU", rd, rs1, rs2, FUNC7_MULDIV, FUNC3_REMU, REGISTER_OPCODE)
+}
+
+// amoR builds an AMO_OPCODE R-type word, packing funct5/aq/rl into funct7
+// the way every AMOSWAP/AMOADD/.../LR/SC encoder below shares.
+func amoR(name string, rd byte, rs1 byte, rs2 byte, funct5 byte, aq bool, rl bool) uint32 {
+	func7 := funct5 << 2
+	if aq {
+		func7 |= 0b10
+	}
+	if rl {
+		func7 |= 0b01
+	}
+	return RType(name, rd, rs1, rs2, func7, AMO_FUNC3, AMO_OPCODE)
+}
+
+// x[rd] = Mem[x[rs1]]; reserves x[rs1] for a matching SC.W
+func LR_W(rd byte, rs1 byte, aq bool, rl bool) uint32 {
+	return amoR("LR.W", rd, rs1, 0, FUNCT5_LR, aq, rl)
+}
+
+// Mem[x[rs1]] = x[rs2] if the reservation from a prior LR.W still holds;
+// x[rd] = 0 on success, 1 on failure
+func SC_W(rd byte, rs1 byte, rs2 byte, aq bool, rl bool) uint32 {
+	return amoR("SC.W", rd, rs1, rs2, FUNCT5_SC, aq, rl)
+}
+
+// x[rd] = Mem[x[rs1]]; Mem[x[rs1]] = x[rs2]
+func AMOSWAP_W(rd byte, rs1 byte, rs2 byte, aq bool, rl bool) uint32 {
+	return amoR("AMOSWAP.W", rd, rs1, rs2, FUNCT5_AMOSWAP, aq, rl)
+}
+
+// x[rd] = Mem[x[rs1]]; Mem[x[rs1]] = x[rd] + x[rs2]
+func AMOADD_W(rd byte, rs1 byte, rs2 byte, aq bool, rl bool) uint32 {
+	return amoR("AMOADD.W", rd, rs1, rs2, FUNCT5_AMOADD, aq, rl)
+}
+
+// x[rd] = Mem[x[rs1]]; Mem[x[rs1]] = x[rd] & x[rs2]
+func AMOAND_W(rd byte, rs1 byte, rs2 byte, aq bool, rl bool) uint32 {
+	return amoR("AMOAND.W", rd, rs1, rs2, FUNCT5_AMOAND, aq, rl)
+}
+
+// x[rd] = Mem[x[rs1]]; Mem[x[rs1]] = x[rd] | x[rs2]
+func AMOOR_W(rd byte, rs1 byte, rs2 byte, aq bool, rl bool) uint32 {
+	return amoR("AMOOR.W", rd, rs1, rs2, FUNCT5_AMOOR, aq, rl)
+}
+
+// x[rd] = Mem[x[rs1]]; Mem[x[rs1]] = x[rd] ^ x[rs2]
+func AMOXOR_W(rd byte, rs1 byte, rs2 byte, aq bool, rl bool) uint32 {
+	return amoR("AMOXOR.W", rd, rs1, rs2, FUNCT5_AMOXOR, aq, rl)
+}
+
+// x[rd] = Mem[x[rs1]]; Mem[x[rs1]] = min(int32(x[rd]), int32(x[rs2]))
+func AMOMIN_W(rd byte, rs1 byte, rs2 byte, aq bool, rl bool) uint32 {
+	return amoR("AMOMIN.W", rd, rs1, rs2, FUNCT5_AMOMIN, aq, rl)
+}
+
+// x[rd] = Mem[x[rs1]]; Mem[x[rs1]] = max(int32(x[rd]), int32(x[rs2]))
+func AMOMAX_W(rd byte, rs1 byte, rs2 byte, aq bool, rl bool) uint32 {
+	return amoR("AMOMAX.W", rd, rs1, rs2, FUNCT5_AMOMAX, aq, rl)
+}
+
+// x[rd] = Mem[x[rs1]]; Mem[x[rs1]] = min(uint32(x[rd]), uint32(x[rs2]))
+func AMOMINU_W(rd byte, rs1 byte, rs2 byte, aq bool, rl bool) uint32 {
+	return amoR("AMOMINU.W", rd, rs1, rs2, FUNCT5_AMOMINU, aq, rl)
+}
+
+// x[rd] = Mem[x[rs1]]; Mem[x[rs1]] = max(uint32(x[rd]), uint32(x[rs2]))
+func AMOMAXU_W(rd byte, rs1 byte, rs2 byte, aq bool, rl bool) uint32 {
+	return amoR("AMOMAXU.W", rd, rs1, rs2, FUNCT5_AMOMAXU, aq, rl)
+}
+
+// FENCE orders prior pred-set accesses before later succ-set accesses;
+// a no-op in this single-hart, in-order pipeline.
+func FENCE(pred byte, succ byte) uint32 {
+	imm := int32(pred&0xF)<<4 | int32(succ&0xF)
+	return IType("FENCE", 0, 0, imm, 0b000, FENCE_OPCODE)
+}
+
+// FENCE.I flushes the instruction fetch stream; also a no-op here since
+// there is no separate fetch cache ahead of ROM/RAM.
+func FENCE_I() uint32 {
+	return IType("FENCE.I", 0, 0, 0, 0b001, FENCE_OPCODE)
+}
+
 // Mem[rs1 + imm] = rs2[7:0]
 func SB(rs1 byte, rs2 byte, imm int32) uint32 {
 	// imm[11:5] rs2 rs1 000 imm[4:0] 0100011
@@ -498,3 +785,202 @@ func JALR(rd byte, rs1 byte, imm int32) uint32 {
 	// imm[11:0] rs1 000 rd 1100111
 	return IType("JALR", rd, rs1, imm, 0b000, JALR_OPCODE)
 }
+
+func BEQ(rs1 byte, rs2 byte, imm int32) uint32 {
+	return BType("BEQ", rs1, rs2, imm, FUNC3_BEQ, BRANCH_OPCODE)
+}
+
+func BNE(rs1 byte, rs2 byte, imm int32) uint32 {
+	return BType("BNE", rs1, rs2, imm, FUNC3_BNE, BRANCH_OPCODE)
+}
+
+func BLT(rs1 byte, rs2 byte, imm int32) uint32 {
+	return BType("BLT", rs1, rs2, imm, FUNC3_BLT, BRANCH_OPCODE)
+}
+
+func BGE(rs1 byte, rs2 byte, imm int32) uint32 {
+	return BType("BGE", rs1, rs2, imm, FUNC3_BGE, BRANCH_OPCODE)
+}
+
+func BLTU(rs1 byte, rs2 byte, imm int32) uint32 {
+	return BType("BLTU", rs1, rs2, imm, FUNC3_BLTU, BRANCH_OPCODE)
+}
+
+func BGEU(rs1 byte, rs2 byte, imm int32) uint32 {
+	return BType("BGEU", rs1, rs2, imm, FUNC3_BGEU, BRANCH_OPCODE)
+}
+
+// ECALL requests a trap into machine mode, e.g. for a syscall.
+func ECALL() uint32 {
+	return IType("ECALL", 0, 0, SYSTEM_IMM_ECALL, 0b000, SYSTEM_OPCODE)
+}
+
+// EBREAK requests a trap into machine mode for a debugger breakpoint.
+func EBREAK() uint32 {
+	return IType("EBREAK", 0, 0, SYSTEM_IMM_EBREAK, 0b000, SYSTEM_OPCODE)
+}
+
+// MRET returns from a machine-mode trap handler to mepc.
+func MRET() uint32 {
+	return IType("MRET", 0, 0, SYSTEM_IMM_MRET, 0b000, SYSTEM_OPCODE)
+}
+
+// x[rd] = CSRs[csr]; CSRs[csr] = x[rs1]
+func CSRRW(rd byte, csr int32, rs1 byte) uint32 {
+	return IType("CSRRW", rd, rs1, csr, FUNC3_CSRRW, SYSTEM_OPCODE)
+}
+
+// x[rd] = CSRs[csr]; CSRs[csr] |= x[rs1]
+func CSRRS(rd byte, csr int32, rs1 byte) uint32 {
+	return IType("CSRRS", rd, rs1, csr, FUNC3_CSRRS, SYSTEM_OPCODE)
+}
+
+// x[rd] = CSRs[csr]; CSRs[csr] &^= x[rs1]
+func CSRRC(rd byte, csr int32, rs1 byte) uint32 {
+	return IType("CSRRC", rd, rs1, csr, FUNC3_CSRRC, SYSTEM_OPCODE)
+}
+
+// x[rd] = CSRs[csr]; CSRs[csr] = zimm (rs1 field holds the 5-bit immediate)
+func CSRRWI(rd byte, csr int32, zimm byte) uint32 {
+	return IType("CSRRWI", rd, zimm, csr, FUNC3_CSRRWI, SYSTEM_OPCODE)
+}
+
+// x[rd] = CSRs[csr]; CSRs[csr] |= zimm
+func CSRRSI(rd byte, csr int32, zimm byte) uint32 {
+	return IType("CSRRSI", rd, zimm, csr, FUNC3_CSRRSI, SYSTEM_OPCODE)
+}
+
+// x[rd] = CSRs[csr]; CSRs[csr] &^= zimm
+func CSRRCI(rd byte, csr int32, zimm byte) uint32 {
+	return IType("CSRRCI", rd, zimm, csr, FUNC3_CSRRCI, SYSTEM_OPCODE)
+}
+
+// cReg expands a compressed 3-bit register field (x8-x15, as used by rs1'/
+// rs2'/rd' in the RVC quadrant-0/1 formats) to its full 5-bit register number.
+func cReg(r byte) byte {
+	return r + 8
+}
+
+// decodeCJImm reassembles the scrambled 11-bit jump offset carried by
+// C.J/C.JAL: instruction bits imm[11|4|9:8|10|6|7|3:1|5] at bit positions
+// 12 down to 2, sign-extended as a 12-bit (LSB-implicit-0) value.
+func decodeCJImm(h uint32) int32 {
+	imm := (Bits(h, 12, 12) << 11) |
+		(Bits(h, 11, 11) << 4) |
+		(Bits(h, 9, 10) << 8) |
+		(Bits(h, 8, 8) << 10) |
+		(Bits(h, 7, 7) << 6) |
+		(Bits(h, 6, 6) << 7) |
+		(Bits(h, 3, 5) << 1) |
+		(Bits(h, 2, 2) << 5)
+	return SignExtend(imm, 12)
+}
+
+// decodeCBImm reassembles the scrambled 8-bit branch offset carried by
+// C.BEQZ/C.BNEZ: instruction bits offset[8|4:3|7:6|2:1|5], sign-extended as
+// a 9-bit (LSB-implicit-0) value.
+func decodeCBImm(h uint32) int32 {
+	imm := (Bits(h, 12, 12) << 8) |
+		(Bits(h, 10, 11) << 3) |
+		(Bits(h, 5, 6) << 6) |
+		(Bits(h, 3, 4) << 1) |
+		(Bits(h, 2, 2) << 5)
+	return SignExtend(imm, 9)
+}
+
+// Decompress expands a 16-bit RVC instruction into its canonical 32-bit
+// equivalent, the way InstructionFetchStage handles it: bits [1:0] of the
+// halfword select the quadrant, and != 0b11 means "compressed" (0b11 means
+// "this halfword is the low half of an ordinary 32-bit instruction").
+// ok is false for an RVC encoding this pass doesn't recognize (reserved
+// encodings, or forms outside the subset implemented here).
+func Decompress(half uint16) (uint32, bool) {
+	h := uint32(half)
+	if h&0b11 == 0b11 {
+		return 0, false
+	}
+	funct3 := byte((h >> 13) & 0b111)
+	rdRs1 := byte((h >> 7) & 0x1F) // full 5-bit rd/rs1 field (quadrants 1 and 2)
+
+	switch h & 0b11 {
+	case 0b00: // quadrant 0: compact loads/stores over x8-x15
+		rdp := cReg(byte((h >> 2) & 0b111))
+		rs1p := cReg(byte((h >> 7) & 0b111))
+		if funct3 == 0b010 { // C.LW
+			uimm := (Bits(h, 10, 12) << 3) | (Bits(h, 6, 6) << 2) | (Bits(h, 5, 5) << 6)
+			return LW(rdp, rs1p, int32(uimm)), true
+		}
+		return 0, false
+
+	case 0b01: // quadrant 1: ALU immediates, branches, jumps
+		switch funct3 {
+		case 0b000: // C.ADDI (rd==0 is C.NOP)
+			imm := SignExtend((Bits(h, 12, 12)<<5)|Bits(h, 2, 6), 6)
+			return ADDI(rdRs1, rdRs1, imm), true
+		case 0b010: // C.LI
+			imm := SignExtend((Bits(h, 12, 12)<<5)|Bits(h, 2, 6), 6)
+			return ADDI(rdRs1, 0, imm), true
+		case 0b011: // C.LUI
+			if rdRs1 == 0 || rdRs1 == 2 {
+				return 0, false // reserved / C.ADDI16SP, not handled here
+			}
+			imm := SignExtend((Bits(h, 12, 12)<<17)|(Bits(h, 2, 6)<<12), 18)
+			return LUI(rdRs1, imm>>12), true
+		case 0b100: // C.SRLI/C.SRAI/C.ANDI/C.SUB/C.XOR/C.OR/C.AND
+			rdp := cReg(byte((h >> 7) & 0b111))
+			shamt := byte((Bits(h, 12, 12) << 5) | Bits(h, 2, 6))
+			switch (h >> 10) & 0b11 {
+			case 0b00:
+				return SRLI(rdp, rdp, shamt), true
+			case 0b01:
+				return SRAI(rdp, rdp, shamt), true
+			case 0b10:
+				imm := SignExtend((Bits(h, 12, 12)<<5)|Bits(h, 2, 6), 6)
+				return ANDI(rdp, rdp, imm), true
+			default: // 0b11
+				rs2p := cReg(byte((h >> 2) & 0b111))
+				switch (h >> 5) & 0b11 {
+				case 0b00:
+					return SUB(rdp, rdp, rs2p), true
+				case 0b01:
+					return XOR(rdp, rdp, rs2p), true
+				case 0b10:
+					return OR(rdp, rdp, rs2p), true
+				default: // 0b11
+					return AND(rdp, rdp, rs2p), true
+				}
+			}
+		case 0b101: // C.J
+			return JAL(0, decodeCJImm(h)), true
+		case 0b110: // C.BEQZ
+			return BEQ(cReg(byte((h>>7)&0b111)), 0, decodeCBImm(h)), true
+		case 0b111: // C.BNEZ
+			return BNE(cReg(byte((h>>7)&0b111)), 0, decodeCBImm(h)), true
+		}
+		return 0, false
+
+	default: // 0b10, quadrant 2: stack-relative ops and the CR-format group
+		switch funct3 {
+		case 0b000: // C.SLLI
+			shamt := byte((Bits(h, 12, 12) << 5) | Bits(h, 2, 6))
+			return SLLI(rdRs1, rdRs1, shamt), true
+		case 0b100: // C.JR/C.JALR/C.MV/C.ADD
+			rs2 := byte((h >> 2) & 0x1F)
+			isAdd := (h>>12)&0b1 != 0
+			if rs2 == 0 {
+				if rdRs1 == 0 {
+					return 0, false // reserved (would be C.EBREAK, not handled here)
+				}
+				if isAdd {
+					return JALR(1, rdRs1, 0), true // C.JALR
+				}
+				return JALR(0, rdRs1, 0), true // C.JR
+			}
+			if isAdd {
+				return ADD(rdRs1, rdRs1, rs2), true // C.ADD
+			}
+			return ADD(rdRs1, 0, rs2), true // C.MV
+		}
+		return 0, false
+	}
+}
@@ -0,0 +1,44 @@
+package pipeline
+
+import "testing"
+
+func TestBTypeRoundTrip(t *testing.T) {
+	cases := []struct {
+		name  string
+		enc   func(rs1, rs2 byte, imm int32) uint32
+		func3 byte
+	}{
+		{"BEQ", BEQ, FUNC3_BEQ},
+		{"BNE", BNE, FUNC3_BNE},
+		{"BLT", BLT, FUNC3_BLT},
+		{"BGE", BGE, FUNC3_BGE},
+		{"BLTU", BLTU, FUNC3_BLTU},
+		{"BGEU", BGEU, FUNC3_BGEU},
+	}
+
+	imms := []int32{0, 4, -4, 2046, -2048, 8, -8}
+
+	for _, c := range cases {
+		for _, imm := range imms {
+			rs1, rs2 := byte(5), byte(10)
+			word := c.enc(rs1, rs2, imm)
+
+			ins, ok := Decode(word).(B_INS)
+			if !ok {
+				t.Fatalf("%s imm=%d: Decode did not return a B_INS", c.name, imm)
+			}
+			if ins.Opcode != BRANCH_OPCODE {
+				t.Fatalf("%s imm=%d: Opcode = 0x%x; want 0x%x", c.name, imm, ins.Opcode, BRANCH_OPCODE)
+			}
+			if ins.Funct3 != c.func3 {
+				t.Fatalf("%s imm=%d: Funct3 = 0x%x; want 0x%x", c.name, imm, ins.Funct3, c.func3)
+			}
+			if ins.Rs1 != rs1 || ins.Rs2 != rs2 {
+				t.Fatalf("%s imm=%d: Rs1=%d Rs2=%d; want Rs1=%d Rs2=%d", c.name, imm, ins.Rs1, ins.Rs2, rs1, rs2)
+			}
+			if ins.Imm != imm {
+				t.Fatalf("%s imm=%d: round-tripped Imm = %d", c.name, imm, ins.Imm)
+			}
+		}
+	}
+}
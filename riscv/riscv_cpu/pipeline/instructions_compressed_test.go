@@ -0,0 +1,105 @@
+package pipeline
+
+import "testing"
+
+// The helpers below assemble RVC halfwords directly from the spec's bit
+// layout, independently of Decompress's own extraction, so the test is an
+// actual round trip rather than Decompress checking its own math.
+
+func rvcCI(funct3 byte, rdRs1 byte, raw6 int32) uint16 {
+	u := uint32(raw6) & 0x3F
+	bit12 := (u >> 5) & 1
+	bits6_2 := u & 0x1F
+	return uint16(uint32(funct3)<<13 | bit12<<12 | uint32(rdRs1)<<7 | bits6_2<<2 | 0b01)
+}
+
+// rdpCode/rs1pCode/rs2pCode below are the raw 3-bit compressed-register
+// codes (0-7, selecting x8-x15), not the expanded register number.
+
+func rvcCB2(rdpCode byte, shamtOrImm int32, bits11_10 uint32) uint16 {
+	u := uint32(shamtOrImm) & 0x3F
+	bit12 := (u >> 5) & 1
+	bits6_2 := u & 0x1F
+	return uint16(0b100<<13 | bit12<<12 | bits11_10<<10 | uint32(rdpCode)<<7 | bits6_2<<2 | 0b01)
+}
+
+func rvcCA(rdpCode byte, funct2 uint32, rs2pCode byte) uint16 {
+	return uint16(0b100<<13 | 0b11<<10 | uint32(rdpCode)<<7 | funct2<<5 | uint32(rs2pCode)<<2 | 0b01)
+}
+
+func rvcCR(bit12 uint32, rdRs1 byte, rs2 byte) uint16 {
+	return uint16(0b100<<13 | bit12<<12 | uint32(rdRs1)<<7 | uint32(rs2)<<2 | 0b10)
+}
+
+func rvcSlli(rd byte, shamt int32) uint16 {
+	u := uint32(shamt) & 0x3F
+	bit12 := (u >> 5) & 1
+	bits6_2 := u & 0x1F
+	return uint16(0b000<<13 | bit12<<12 | uint32(rd)<<7 | bits6_2<<2 | 0b10)
+}
+
+func rvcLW(rdpCode byte, rs1pCode byte, uimm int32) uint16 {
+	u := uint32(uimm)
+	bits12_10 := (u >> 3) & 0x7
+	bit6 := (u >> 2) & 1
+	bit5 := (u >> 6) & 1
+	return uint16(0b010<<13 | bits12_10<<10 | uint32(rs1pCode)<<7 | bit6<<6 | bit5<<5 | uint32(rdpCode)<<2 | 0b00)
+}
+
+func rvcJ(imm int32) uint16 {
+	u := uint32(imm)
+	b := func(bit uint) uint32 { return (u >> bit) & 1 }
+	packed := b(11)<<12 | b(4)<<11 | b(9)<<10 | b(8)<<9 | b(10)<<8 | b(6)<<7 | b(7)<<6 | b(3)<<5 | b(2)<<4 | b(1)<<3 | b(5)<<2
+	return uint16(0b101<<13 | packed | 0b01)
+}
+
+func rvcB(funct3 byte, rs1pCode byte, imm int32) uint16 {
+	u := uint32(imm)
+	b := func(bit uint) uint32 { return (u >> bit) & 1 }
+	packed := b(8)<<12 | b(4)<<11 | b(3)<<10 | b(7)<<6 | b(6)<<5 | b(2)<<4 | b(1)<<3 | b(5)<<2
+	return uint16(uint32(funct3)<<13 | uint32(rs1pCode)<<7 | packed | 0b01)
+}
+
+func TestDecompress(t *testing.T) {
+	cases := []struct {
+		name string
+		half uint16
+		want uint32
+	}{
+		{"C.ADDI", rvcCI(0b000, 10, 5), ADDI(10, 10, 5)},
+		{"C.LI", rvcCI(0b010, 9, -3), ADDI(9, 0, -3)},
+		{"C.LUI", rvcCI(0b011, 5, 3), LUI(5, 3)},
+		{"C.SLLI", rvcSlli(15, 7), SLLI(15, 15, 7)},
+		{"C.SRLI", rvcCB2(4, 7, 0b00), SRLI(cReg(4), cReg(4), 7)},
+		{"C.SRAI", rvcCB2(4, 7, 0b01), SRAI(cReg(4), cReg(4), 7)},
+		{"C.ANDI", rvcCB2(4, -2, 0b10), ANDI(cReg(4), cReg(4), -2)},
+		{"C.SUB", rvcCA(1, 0b00, 3), SUB(cReg(1), cReg(1), cReg(3))},
+		{"C.XOR", rvcCA(1, 0b01, 3), XOR(cReg(1), cReg(1), cReg(3))},
+		{"C.OR", rvcCA(1, 0b10, 3), OR(cReg(1), cReg(1), cReg(3))},
+		{"C.AND", rvcCA(1, 0b11, 3), AND(cReg(1), cReg(1), cReg(3))},
+		{"C.MV", rvcCR(0, 14, 7), ADD(14, 0, 7)},
+		{"C.ADD", rvcCR(1, 14, 7), ADD(14, 14, 7)},
+		{"C.JR", rvcCR(0, 9, 0), JALR(0, 9, 0)},
+		{"C.JALR", rvcCR(1, 9, 0), JALR(1, 9, 0)},
+		{"C.LW", rvcLW(2, 3, 4), LW(cReg(2), cReg(3), 4)},
+		{"C.J", rvcJ(100), JAL(0, 100)},
+		{"C.BEQZ", rvcB(0b110, 2, 20), BEQ(cReg(2), 0, 20)},
+		{"C.BNEZ", rvcB(0b111, 2, -20), BNE(cReg(2), 0, -20)},
+	}
+
+	for _, c := range cases {
+		got, ok := Decompress(c.half)
+		if !ok {
+			t.Fatalf("%s: Decompress(0x%04X) reported unrecognized", c.name, c.half)
+		}
+		if got != c.want {
+			t.Fatalf("%s: Decompress(0x%04X) = 0x%08X; want 0x%08X", c.name, c.half, got, c.want)
+		}
+	}
+}
+
+func TestDecompressRejectsFullWidth(t *testing.T) {
+	if _, ok := Decompress(0xFFFF); ok {
+		t.Fatalf("Decompress should reject a halfword with bits[1:0]==11 (not RVC)")
+	}
+}
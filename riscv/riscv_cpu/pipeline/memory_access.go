@@ -1,28 +1,10 @@
 package pipeline
 
 import (
-	"fmt"
+	"io"
 	. "riscv/system_interface"
 )
 
-type ExecutedValues struct {
-	isAluOp   bool
-	isStoreOp bool
-	isLoadOp  bool
-	isLUIOp   bool
-	isJUMPOp  bool
-
-	writeBackValue uint32
-	rd             byte
-	rs1V           uint32
-	rs2V           uint32
-
-	imm32 int32
-	func3 byte
-
-	pcPlus4 uint32
-}
-
 type MemoryAccessParams struct {
 	bus SystemInterface
 
@@ -50,6 +32,32 @@ const (
 	STORE_FUNC3_SW = 0b010
 )
 
+// loadWidthMask/storeWidthMask return the RVFI byte-lane mask for a load/
+// store's func3, one bit per byte touched (0x1/0x3/0xF for a byte/half/
+// word access), ignoring address alignment within the word the way
+// mem_rmask/mem_wmask do in the Sail RVFI model this mirrors.
+func loadWidthMask(func3 byte) byte {
+	switch func3 & 0b011 {
+	case LOAD_FUNC3_LB:
+		return 0x1
+	case LOAD_FUNC3_LH:
+		return 0x3
+	default: // LOAD_FUNC3_LW
+		return 0xF
+	}
+}
+
+func storeWidthMask(func3 byte) byte {
+	switch func3 {
+	case STORE_FUNC3_SB:
+		return 0x1
+	case STORE_FUNC3_SH:
+		return 0x3
+	default: // STORE_FUNC3_SW
+		return 0xF
+	}
+}
+
 type MemoryAccessStage struct {
 	shouldStall          func() bool
 	getExecutionValuesIn func() ExecutedValues
@@ -57,9 +65,68 @@ type MemoryAccessStage struct {
 	bus SystemInterface
 
 	writeBackValue RUint32
+	insn           RUint32
 	rd             RByte
+	rs1Addr        RByte
+	rs2Addr        RByte
+	rs1V           RUint32
+	rs2V           RUint32
+	pc             RUint32
+	pcNext         RUint32
 
 	writeBackValueValid RBool
+
+	isLoadOp  RBool
+	isStoreOp RBool
+
+	// memAddr/memRmask/memWmask/memRdata/memWdata latch this instruction's
+	// bus access for RVFI: memRmask/memWmask are non-zero only for a load/
+	// store respectively, one bit per byte lane touched (0x1/0x3/0xF for a
+	// byte/half/word access), the same width encoding RVFISink consumers
+	// expect from Sail/Spike traces.
+	memAddr  RUint32
+	memRmask RByte
+	memWmask RByte
+	memRdata RUint32
+	memWdata RUint32
+
+	tracer    Tracer
+	stalled   bool
+	cycleCost CycleCost
+
+	// AMO/LR/SC read-modify-write sub-phase bookkeeping. These are plain
+	// fields, not RBool/RByte latches, the same way stalled is: they track
+	// progress across repeated Compute() calls while a bus transaction is
+	// in flight, not pipeline state that should latch at cycle boundaries.
+	amoReadDone    bool
+	amoLoadedValue uint32
+	amoScResolved  bool
+	amoScSuccess   bool
+
+	// memFault/memFaultAddr/memFaultIsStore/memFaultErr report a load/store
+	// the bus rejected (misaligned access, or no device mapped) this
+	// Compute call, for RVI32System to turn into a CSR trap the same way it
+	// already does for ECALL/EBREAK. memFaultErr carries the bus's error so
+	// handleMemFault can tell a misaligned access (wraps
+	// ErrMisalignedAccess) from an access to unmapped memory apart. Plain
+	// fields, reset every Compute call, not RBool/RByte latches: a fault is
+	// consumed the same cycle it's raised.
+	memFault        bool
+	memFaultAddr    uint32
+	memFaultIsStore bool
+	memFaultErr     error
+
+	// illegalOp reports a load/store with a reserved func3 bit pattern, or
+	// an AMO whose tag combineAmo doesn't recognize, that this Compute call
+	// couldn't execute at all (the bus was never even touched). Same
+	// reset-every-Compute-call convention as memFault, but a distinct flag:
+	// it traps as CauseIllegalInstruction, not a misaligned/unmapped access.
+	illegalOp bool
+
+	reservationOut func(addr uint32, valid bool)
+	reservationIn  func(addr uint32) bool
+	lockAmo        func()
+	unlockAmo      func()
 }
 
 func NewMemoryAccessStage(params *MemoryAccessParams) *MemoryAccessStage {
@@ -69,116 +136,596 @@ func NewMemoryAccessStage(params *MemoryAccessParams) *MemoryAccessStage {
 	ma.shouldStall = params.shouldStall
 	ma.getExecutionValuesIn = params.getExecutionValuesIn
 	ma.bus = params.bus
+	ma.tracer = NopTracer{}
+	ma.cycleCost = DefaultCycleCost
+	ma.reservationOut = func(addr uint32, valid bool) {}
+	ma.reservationIn = func(addr uint32) bool { return false }
+	ma.lockAmo = func() {}
+	ma.unlockAmo = func() {}
 
 	return ma
 }
 
+// SetReservation attaches the LR.W/SC.W reservation callbacks: out reports
+// a new reservation (or its loss) to the CPU, in asks whether the
+// reservation for addr still holds. Pass nil for either to go back to
+// acting as if no reservation is ever granted.
+func (ma *MemoryAccessStage) SetReservation(out func(addr uint32, valid bool), in func(addr uint32) bool) {
+	if out == nil {
+		out = func(addr uint32, valid bool) {}
+	}
+	if in == nil {
+		in = func(addr uint32) bool { return false }
+	}
+	ma.reservationOut = out
+	ma.reservationIn = in
+}
+
+// SetAmoLock attaches lock/unlock hooks held around an AMO's
+// read-modify-write, so a future multi-hart configuration can serialize
+// concurrent AMOs to the same bus without re-plumbing MemoryAccessStage.
+// A single-hart RVI32System doesn't need real mutual exclusion, so the
+// default is a no-op pair.
+func (ma *MemoryAccessStage) SetAmoLock(lock func(), unlock func()) {
+	if lock == nil {
+		lock = func() {}
+	}
+	if unlock == nil {
+		unlock = func() {}
+	}
+	ma.lockAmo = lock
+	ma.unlockAmo = unlock
+}
+
+// SetTracer attaches a Tracer to receive this stage's trace output. Pass
+// nil to go back to discarding it.
+func (ma *MemoryAccessStage) SetTracer(tracer Tracer) {
+	if tracer == nil {
+		tracer = NopTracer{}
+	}
+	ma.tracer = tracer
+}
+
+// SetCycleCost overrides the per-instruction cycle-cost table
+// MemoryAccessStage consults when GetMemoryAccessCycles is read. Pass the
+// zero value to charge no extra cycles at all.
+func (ma *MemoryAccessStage) SetCycleCost(cost CycleCost) {
+	ma.cycleCost = cost
+}
+
+// GetMemoryAccessCycles returns the extra cycles the instruction currently
+// latched in MEMORY_ACCESS burns, on top of the one cycle every Cycle()
+// call already charges: LoadExtra for a load, StoreExtra for a store.
+func (ma *MemoryAccessStage) GetMemoryAccessCycles() uint64 {
+	var cost uint64
+	if ma.isLoadOp.GetN() {
+		cost += ma.cycleCost.LoadExtra
+	}
+	if ma.isStoreOp.GetN() {
+		cost += ma.cycleCost.StoreExtra
+	}
+	return cost
+}
+
 func (ma *MemoryAccessStage) Compute() {
-	if !ma.shouldStall() {
-		// fmt.Println("@ MEMORY_ACCESS")
-
-		ev := ma.getExecutionValuesIn()
-
-		ma.writeBackValue.SetN(ev.writeBackValue)
-		ma.rd.SetN(ev.rd)
-
-		ma.writeBackValueValid.SetN(ev.isAluOp || ev.isLoadOp || ev.isLUIOp || ev.isJUMPOp)
-
-		addr := uint32(int32(ev.rs1V) + ev.imm32)
-
-		if ev.isStoreOp {
-
-			switch ev.func3 {
-			case STORE_FUNC3_SB:
-				// Store Byte
-				err := ma.bus.Write(addr, ev.rs2V&0xFF, MEMORY_WIDTH_BYTE)
-				fmt.Printf(" SB  Addr=0x%08X, Value=0x%08X, %v \n", addr, ev.rs2V&0xFF, err)
-			case STORE_FUNC3_SH:
-				// Store Halfword
-				err := ma.bus.Write(addr, ev.rs2V&0xFFFF, MEMORY_WIDTH_HALF)
-				fmt.Printf(" SH  Addr=0x%08X, Value=0x%08X, %v \n", addr, ev.rs2V&0xFFFF, err)
-			case STORE_FUNC3_SW:
-				// Store Word
-				err := ma.bus.Write(addr, ev.rs2V, MEMORY_WIDTH_WORD)
-				fmt.Printf(" SW  Addr=0x%08X, Value=0x%08X, %v \n", addr, ev.rs2V, err)
-			default:
-				panic(fmt.Sprintf("Unsupported store func3: 0b%03b", ev.func3))
+	if ma.shouldStall() {
+		return
+	}
+	// fmt.Println("@ MEMORY_ACCESS")
+
+	ev := ma.getExecutionValuesIn()
+
+	ma.memFault = false
+	ma.memFaultErr = nil
+	ma.illegalOp = false
+
+	ma.writeBackValue.SetN(ev.writeBackValue)
+	ma.rd.SetN(ev.rd)
+
+	// Carried through purely for RVFI retire records, the same reason
+	// ExecuteStage threads them from DecodedValues.
+	ma.insn.SetN(ev.insn)
+	ma.rs1Addr.SetN(ev.rs1Addr)
+	ma.rs2Addr.SetN(ev.rs2Addr)
+	ma.rs1V.SetN(ev.rs1V)
+	ma.rs2V.SetN(ev.rs2V)
+	ma.pc.SetN(ev.pc)
+	ma.pcNext.SetN(ev.pcNext)
+
+	ma.writeBackValueValid.SetN(ev.isAluOp || ev.isLoadOp || ev.isLuiOp || ev.isJumpOp || ev.isMulDivOp || ev.isAmoOp)
+	ma.isLoadOp.SetN(ev.isLoadOp)
+	ma.isStoreOp.SetN(ev.isStoreOp)
+
+	// Default to no memory access; the store/load branches below overwrite
+	// these for an instruction that actually touches the bus.
+	ma.memAddr.SetN(0)
+	ma.memRmask.SetN(0)
+	ma.memWmask.SetN(0)
+	ma.memRdata.SetN(0)
+	ma.memWdata.SetN(0)
+
+	addr := uint32(int32(ev.rs1V) + ev.imm32)
+
+	if ev.isStoreOp {
+
+		var ready bool
+		var err error
+
+		switch ev.func3 {
+		case STORE_FUNC3_SB:
+			// Store Byte
+			ready, err = ma.bus.Write(addr, ev.rs2V&0xFF, MEMORY_WIDTH_BYTE)
+			if ready {
+				ma.tracer.Printf(" SB  Addr=0x%08X, Value=0x%08X, %v \n", addr, ev.rs2V&0xFF, err)
+				ma.tracer.OnMemAccess(addr, ev.rs2V&0xFF, 1, true)
 			}
+		case STORE_FUNC3_SH:
+			// Store Halfword
+			ready, err = ma.bus.Write(addr, ev.rs2V&0xFFFF, MEMORY_WIDTH_HALF)
+			if ready {
+				ma.tracer.Printf(" SH  Addr=0x%08X, Value=0x%08X, %v \n", addr, ev.rs2V&0xFFFF, err)
+				ma.tracer.OnMemAccess(addr, ev.rs2V&0xFFFF, 2, true)
+			}
+		case STORE_FUNC3_SW:
+			// Store Word
+			ready, err = ma.bus.Write(addr, ev.rs2V, MEMORY_WIDTH_WORD)
+			if ready {
+				ma.tracer.Printf(" SW  Addr=0x%08X, Value=0x%08X, %v \n", addr, ev.rs2V, err)
+				ma.tracer.OnMemAccess(addr, ev.rs2V, 4, true)
+			}
+		default:
+			// A reserved store func3: no device was ever touched, so just
+			// report it and let cpu.go trap, instead of crashing the
+			// process on a malformed encoding.
+			ma.illegalOp = true
+			ma.stalled = false
+			return
+		}
 
-		} else if ev.isLoadOp {
-			shouldSignExtend := (ev.func3 & 0b100) == 0
-
-			var value uint32
+		ma.stalled = !ready
+		if ready {
+			if err != nil {
+				ma.memFault = true
+				ma.memFaultAddr = addr
+				ma.memFaultIsStore = true
+				ma.memFaultErr = err
+			} else {
+				// Any ordinary store clears an outstanding LR.W
+				// reservation, conservatively, regardless of whether it
+				// targets the reserved address.
+				ma.reservationOut(addr, false)
+				ma.memAddr.SetN(addr)
+				ma.memWmask.SetN(storeWidthMask(ev.func3))
+				ma.memWdata.SetN(ev.rs2V)
+			}
+		}
 
-			switch ev.func3 & 0b011 {
-			case LOAD_FUNC3_LB:
-				// Load Byte (sign-extended)
-				memvalue, err := ma.bus.Read(addr, MEMORY_WIDTH_BYTE)
-				if err != nil {
-					fmt.Printf(" LB/U  ERROR: %s", err.Error())
-					break
-				}
-				if shouldSignExtend {
-					value = uint32(int32(int8(memvalue & 0xFF)))
-					fmt.Printf(" LB  Addr=0x%08X, Value=0x%08X -> R%02d", addr, value, ev.rd)
-				} else {
-					value = memvalue & 0xFF
-					fmt.Printf(" LBU  Addr=0x%08X, Value=0x%08X -> R%02d", addr, value, ev.rd)
-				}
-			case LOAD_FUNC3_LH:
-				// Load Halfword (sign-extended)
-				memvalue, err := ma.bus.Read(addr, MEMORY_WIDTH_HALF)
-				if err != nil {
-					fmt.Printf(" LH/U  ERROR: %s", err.Error())
-					break
+	} else if ev.isLoadOp {
+		shouldSignExtend := (ev.func3 & 0b100) == 0
+
+		var value uint32
+		var ready bool
+
+		switch ev.func3 & 0b011 {
+		case LOAD_FUNC3_LB:
+			// Load Byte (sign-extended)
+			memvalue, r, err := ma.bus.Read(addr, MEMORY_WIDTH_BYTE)
+			ready = r
+			if err != nil {
+				ma.tracer.Printf(" LB/U  ERROR: %s", err.Error())
+				if ready {
+					ma.memFault = true
+					ma.memFaultAddr = addr
+					ma.memFaultErr = err
 				}
-				if shouldSignExtend {
-					value = uint32(int32(int16(memvalue & 0xFFFF)))
-					fmt.Printf(" LH  Addr=0x%08X, Value=0x%08X -> R%02d", addr, value, ev.rd)
-				} else {
-					value = memvalue & 0xFFFF
-					fmt.Printf(" LHU Addr=0x%08X, Value=0x%08X -> R%02d", addr, value, ev.rd)
+				break
+			}
+			if !ready {
+				break
+			}
+			if shouldSignExtend {
+				value = uint32(int32(int8(memvalue & 0xFF)))
+				ma.tracer.Printf(" LB  Addr=0x%08X, Value=0x%08X -> R%02d", addr, value, ev.rd)
+			} else {
+				value = memvalue & 0xFF
+				ma.tracer.Printf(" LBU  Addr=0x%08X, Value=0x%08X -> R%02d", addr, value, ev.rd)
+			}
+			ma.tracer.OnMemAccess(addr, value, 1, false)
+		case LOAD_FUNC3_LH:
+			// Load Halfword (sign-extended)
+			memvalue, r, err := ma.bus.Read(addr, MEMORY_WIDTH_HALF)
+			ready = r
+			if err != nil {
+				ma.tracer.Printf(" LH/U  ERROR: %s", err.Error())
+				if ready {
+					ma.memFault = true
+					ma.memFaultAddr = addr
+					ma.memFaultErr = err
 				}
-			case LOAD_FUNC3_LW:
-				// Load Word
-				memvalue, err := ma.bus.Read(addr, MEMORY_WIDTH_WORD)
-				if err != nil {
-					fmt.Printf(" LW   ERROR: %s", err.Error())
-					break
+				break
+			}
+			if !ready {
+				break
+			}
+			if shouldSignExtend {
+				value = uint32(int32(int16(memvalue & 0xFFFF)))
+				ma.tracer.Printf(" LH  Addr=0x%08X, Value=0x%08X -> R%02d", addr, value, ev.rd)
+			} else {
+				value = memvalue & 0xFFFF
+				ma.tracer.Printf(" LHU Addr=0x%08X, Value=0x%08X -> R%02d", addr, value, ev.rd)
+			}
+			ma.tracer.OnMemAccess(addr, value, 2, false)
+		case LOAD_FUNC3_LW:
+			// Load Word
+			memvalue, r, err := ma.bus.Read(addr, MEMORY_WIDTH_WORD)
+			ready = r
+			if err != nil {
+				ma.tracer.Printf(" LW   ERROR: %s", err.Error())
+				if ready {
+					ma.memFault = true
+					ma.memFaultAddr = addr
+					ma.memFaultErr = err
 				}
-				value = memvalue
-				fmt.Printf(" LW  Addr=0x%08X, Value=0x%08X -> R%02d", addr, value, ev.rd)
-			default:
-				panic(fmt.Sprintf("Unsupported load func3: 0b%03b", ev.func3))
+				break
+			}
+			if !ready {
+				break
 			}
+			value = memvalue
+			ma.tracer.Printf(" LW  Addr=0x%08X, Value=0x%08X -> R%02d", addr, value, ev.rd)
+			ma.tracer.OnMemAccess(addr, value, 4, false)
+		default:
+			// A reserved load func3: no device was ever touched, so just
+			// report it and let cpu.go trap, instead of crashing the
+			// process on a malformed encoding.
+			ma.illegalOp = true
+			ma.stalled = false
+			return
+		}
+
+		ma.stalled = !ready
+		if ready {
+			ma.writeBackValue.SetN(value)
+			ma.memAddr.SetN(addr)
+			ma.memRmask.SetN(loadWidthMask(ev.func3))
+			ma.memRdata.SetN(value)
+		}
+	} else if ev.isLuiOp {
+		ma.stalled = false
+		ma.writeBackValue.SetN(uint32(ev.imm32))
+		ma.tracer.Printf(" LUI rd=R%02d  imm=0x%08X", ev.rd, uint32(ev.imm32))
+	} else if ev.isJumpOp {
+		ma.stalled = false
+		ma.writeBackValue.SetN(ev.pcPlus4)
+		ma.tracer.Printf("JUMP : JAL/R  return_addr=0x%08X", ev.pcPlus4)
+	} else if ev.isAmoOp {
+		ma.computeAmo(ev, addr)
+	} else {
+		ma.stalled = false
+	}
+}
 
+// computeAmo runs LR.W/SC.W/the AMO read-modify-write family. A plain AMO
+// needs a read and a write in the same MEMORY_ACCESS visit; amoReadDone
+// tracks that sub-phase across repeated Compute() calls the way
+// InstructionFetchStage.fetchIssued tracks a fetch already in flight, so a
+// multi-cycle bus latency doesn't re-issue the read once it completes.
+func (ma *MemoryAccessStage) computeAmo(ev ExecutedValues, addr uint32) {
+	switch ev.amoTag {
+	case TagLR:
+		value, ready, err := ma.bus.Read(addr, MEMORY_WIDTH_WORD)
+		if err != nil {
+			ma.tracer.Printf(" LR.W  ERROR: %s", err.Error())
+		}
+		ma.stalled = !ready
+		if ready {
+			ma.reservationOut(addr, true)
 			ma.writeBackValue.SetN(value)
-		} else if ev.isLUIOp {
-			ma.writeBackValue.SetN(uint32(ev.imm32))
-			fmt.Printf(" LUI rd=R%02d  imm=0x%08X", ev.rd, uint32(ev.imm32))
-		} else if ev.isJUMPOp {
-			ma.writeBackValue.SetN(ev.pcPlus4)
-			fmt.Printf("JUMP : JAL/R  return_addr=0x%08X", ev.pcPlus4)
+			ma.tracer.Printf(" LR.W  Addr=0x%08X, Value=0x%08X -> R%02d", addr, value, ev.rd)
+		}
+
+	case TagSC:
+		if !ma.amoScResolved {
+			ma.amoScSuccess = ma.reservationIn(addr)
+			ma.reservationOut(addr, false) // SC always consumes the reservation
+			ma.amoScResolved = true
+		}
+		if !ma.amoScSuccess {
+			ma.stalled = false
+			ma.writeBackValue.SetN(1)
+			ma.amoScResolved = false
+			ma.tracer.Printf(" SC.W  Addr=0x%08X -> failed", addr)
+			return
 		}
+		ready, err := ma.bus.Write(addr, ev.rs2V, MEMORY_WIDTH_WORD)
+		if err != nil {
+			ma.tracer.Printf(" SC.W  ERROR: %s", err.Error())
+		}
+		ma.stalled = !ready
+		if ready {
+			ma.writeBackValue.SetN(0)
+			ma.amoScResolved = false
+			ma.tracer.Printf(" SC.W  Addr=0x%08X, Value=0x%08X -> succeeded", addr, ev.rs2V)
+		}
+
+	default: // AMOSWAP/AMOADD/AMOAND/AMOOR/AMOXOR/AMOMIN/AMOMAX/AMOMINU/AMOMAXU
+		if !ma.amoReadDone {
+			ma.lockAmo()
+			value, ready, err := ma.bus.Read(addr, MEMORY_WIDTH_WORD)
+			if err != nil {
+				ma.tracer.Printf(" AMO  ERROR: %s", err.Error())
+			}
+			if !ready {
+				ma.unlockAmo()
+				ma.stalled = true
+				return
+			}
+			ma.amoLoadedValue = value
+			ma.amoReadDone = true
+		}
+
+		combined, ok := combineAmo(ev.amoTag, ma.amoLoadedValue, ev.rs2V)
+		if !ok {
+			ma.unlockAmo()
+			ma.illegalOp = true
+			ma.stalled = false
+			ma.amoReadDone = false
+			return
+		}
+		ready, err := ma.bus.Write(addr, combined, MEMORY_WIDTH_WORD)
+		if err != nil {
+			ma.tracer.Printf(" AMO  ERROR: %s", err.Error())
+		}
+		ma.stalled = !ready
+		if ready {
+			ma.unlockAmo()
+			ma.reservationOut(addr, false)
+			ma.writeBackValue.SetN(ma.amoLoadedValue)
+			ma.tracer.Printf(" AMO  Addr=0x%08X, Old=0x%08X, New=0x%08X -> R%02d", addr, ma.amoLoadedValue, combined, ev.rd)
+			ma.amoReadDone = false
+		}
+	}
+}
+
+// combineAmo applies an AMO's read-modify-write operator to the value just
+// read from memory and the rs2 operand, per the RV32A semantics for each
+// funct5 (AMOSWAP/AMOADD/... ); LR.W/SC.W never reach here. ok is false for
+// a tag none of these recognize (LookupDesc failed to resolve the AMO and
+// left amoTag at a stale/zero value), so the caller can report it instead
+// of committing a bogus result.
+func combineAmo(tag SemanticTag, old uint32, operand uint32) (uint32, bool) {
+	switch tag {
+	case TagAmoSwap:
+		return operand, true
+	case TagAmoAdd:
+		return old + operand, true
+	case TagAmoAnd:
+		return old & operand, true
+	case TagAmoOr:
+		return old | operand, true
+	case TagAmoXor:
+		return old ^ operand, true
+	case TagAmoMin:
+		if int32(old) < int32(operand) {
+			return old, true
+		}
+		return operand, true
+	case TagAmoMax:
+		if int32(old) > int32(operand) {
+			return old, true
+		}
+		return operand, true
+	case TagAmoMinu:
+		if old < operand {
+			return old, true
+		}
+		return operand, true
+	case TagAmoMaxu:
+		if old > operand {
+			return old, true
+		}
+		return operand, true
+	default:
+		return 0, false
 	}
 }
 
+// IsStalled reports whether the in-flight load/store transaction is still
+// waiting on the bus, so RVI32System.Cycle can hold MA in MEMORY_ACCESS
+// instead of advancing to WRITE_BACK.
+func (ma *MemoryAccessStage) IsStalled() bool {
+	return ma.stalled
+}
+
+// MemFault reports a load/store the bus rejected this Compute call (a
+// misaligned access, or an address with no device mapped), so
+// RVI32System can turn it into a CSR trap instead of silently letting
+// writeBackValue commit garbage. err is the bus's original error, wrapping
+// ErrMisalignedAccess for the misaligned case, so the caller can pick the
+// matching CSR cause.
+func (ma *MemoryAccessStage) MemFault() (addr uint32, isStore bool, err error, ok bool) {
+	return ma.memFaultAddr, ma.memFaultIsStore, ma.memFaultErr, ma.memFault
+}
+
+// IllegalOp reports a load/store with a reserved func3 bit pattern, or an
+// AMO whose tag combineAmo doesn't recognize, that this Compute call
+// couldn't execute, so RVI32System can turn it into a CSR
+// illegal-instruction trap instead of crashing the process.
+func (ma *MemoryAccessStage) IllegalOp() bool {
+	return ma.illegalOp
+}
+
+// GetForwardOut exposes the value about to be written back (still sitting
+// in this stage's latch, not yet committed by WriteBackStage) so
+// ExecuteStage can bypass a dependent instruction instead of reading a
+// stale regFile entry.
+func (ma *MemoryAccessStage) GetForwardOut() (rd byte, value uint32, valid bool) {
+	return ma.rd.GetN(), ma.writeBackValue.GetN(), ma.writeBackValueValid.GetN()
+}
+
 func (ma *MemoryAccessStage) LatchNext() {
 	ma.writeBackValue.LatchNext()
+	ma.insn.LatchNext()
 	ma.rd.LatchNext()
+	ma.rs1Addr.LatchNext()
+	ma.rs2Addr.LatchNext()
+	ma.rs1V.LatchNext()
+	ma.rs2V.LatchNext()
+	ma.pc.LatchNext()
+	ma.pcNext.LatchNext()
 	ma.writeBackValueValid.LatchNext()
+	ma.isLoadOp.LatchNext()
+	ma.isStoreOp.LatchNext()
+	ma.memAddr.LatchNext()
+	ma.memRmask.LatchNext()
+	ma.memWmask.LatchNext()
+	ma.memRdata.LatchNext()
+	ma.memWdata.LatchNext()
+}
+
+// Serialize writes the MA latch registers that have a Serialize method
+// (writeBackValue/writeBackValueValid/insn/rd/rs1Addr/rs2Addr/rs1V/rs2V/pc/
+// pcNext/memAddr/memRmask/memWmask/memRdata/memWdata; isLoadOp/isStoreOp
+// are RBool flags only ever read via GetN() within the same Compute call
+// that sets them, so they're overwritten before a restored snapshot's
+// stale value could matter and aren't worth snapshotting. writeBackValueValid
+// doesn't get that pass: WriteBackStage.Compute reads it via Get(), the
+// latched value from the cycle before MA.Compute runs again, so a restored
+// snapshot must carry it or the first post-restore write-back silently
+// drops). Order must match Deserialize.
+func (ma *MemoryAccessStage) Serialize(w io.Writer) error {
+	if err := ma.writeBackValue.Serialize(w); err != nil {
+		return err
+	}
+	if err := ma.writeBackValueValid.Serialize(w); err != nil {
+		return err
+	}
+	if err := ma.insn.Serialize(w); err != nil {
+		return err
+	}
+	if err := ma.rd.Serialize(w); err != nil {
+		return err
+	}
+	if err := ma.rs1Addr.Serialize(w); err != nil {
+		return err
+	}
+	if err := ma.rs2Addr.Serialize(w); err != nil {
+		return err
+	}
+	if err := ma.rs1V.Serialize(w); err != nil {
+		return err
+	}
+	if err := ma.rs2V.Serialize(w); err != nil {
+		return err
+	}
+	if err := ma.pc.Serialize(w); err != nil {
+		return err
+	}
+	if err := ma.pcNext.Serialize(w); err != nil {
+		return err
+	}
+	if err := ma.memAddr.Serialize(w); err != nil {
+		return err
+	}
+	if err := ma.memRmask.Serialize(w); err != nil {
+		return err
+	}
+	if err := ma.memWmask.Serialize(w); err != nil {
+		return err
+	}
+	if err := ma.memRdata.Serialize(w); err != nil {
+		return err
+	}
+	return ma.memWdata.Serialize(w)
+}
+
+// Deserialize restores the MA latch registers written by Serialize.
+func (ma *MemoryAccessStage) Deserialize(r io.Reader) error {
+	if err := ma.writeBackValue.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ma.writeBackValueValid.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ma.insn.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ma.rd.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ma.rs1Addr.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ma.rs2Addr.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ma.rs1V.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ma.rs2V.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ma.pc.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ma.pcNext.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ma.memAddr.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ma.memRmask.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ma.memWmask.Deserialize(r); err != nil {
+		return err
+	}
+	if err := ma.memRdata.Deserialize(r); err != nil {
+		return err
+	}
+	return ma.memWdata.Deserialize(r)
 }
 
 type MemoryAccessValues struct {
 	writeBackValid bool
 	writeBackValue uint32
+	insn           uint32
 	rd             byte
+	rs1Addr        byte
+	rs2Addr        byte
+	rs1V           uint32
+	rs2V           uint32
+	pc             uint32
+	pcNext         uint32
+
+	memAddr  uint32
+	memRmask byte
+	memWmask byte
+	memRdata uint32
+	memWdata uint32
 }
 
+// GetMemoryAccessValuesOut returns MA's latched output: what MA finished as
+// of the end of the previous cycle, the value WB consumes as its input
+// this cycle. MA's same-cycle, still-in-flight result is exposed
+// separately by GetForwardOut, for EX's bypass path.
 func (ma *MemoryAccessStage) GetMemoryAccessValuesOut() MemoryAccessValues {
 	return MemoryAccessValues{
-		writeBackValid: ma.writeBackValueValid.GetN(),
-		writeBackValue: ma.writeBackValue.GetN(),
-		rd:             ma.rd.GetN(),
+		writeBackValid: ma.writeBackValueValid.Get(),
+		writeBackValue: ma.writeBackValue.Get(),
+		insn:           ma.insn.Get(),
+		rd:             ma.rd.Get(),
+		rs1Addr:        ma.rs1Addr.Get(),
+		rs2Addr:        ma.rs2Addr.Get(),
+		rs1V:           ma.rs1V.Get(),
+		rs2V:           ma.rs2V.Get(),
+		pc:             ma.pc.Get(),
+		pcNext:         ma.pcNext.Get(),
+
+		memAddr:  ma.memAddr.Get(),
+		memRmask: ma.memRmask.Get(),
+		memWmask: ma.memWmask.Get(),
+		memRdata: ma.memRdata.Get(),
+		memWdata: ma.memWdata.Get(),
 	}
 }
@@ -0,0 +1,42 @@
+package pipeline
+
+// RetireRecord mirrors the retirement interface used by the Sail RISC-V
+// formal model (RVFI) so traces from this simulator can be diffed directly
+// against Sail/Spike for co-simulation.
+type RetireRecord struct {
+	Order uint64 // monotonic retirement counter
+
+	Insn uint32
+	Trap bool
+	Halt bool
+
+	Rs1Addr  byte
+	Rs2Addr  byte
+	Rs1Rdata uint32
+	Rs2Rdata uint32
+
+	RdAddr  byte
+	RdWdata uint32
+
+	PcRdata uint32
+	PcWdata uint32
+
+	MemAddr  uint32
+	MemRmask byte
+	MemWmask byte
+	MemRdata uint32
+	MemWdata uint32
+}
+
+// RVFISink receives one RetireRecord per committed instruction. Implementers
+// can forward records to a co-simulation harness, a trace file, or a
+// diffing tool; a nil sink means RVFI tracing is disabled.
+type RVFISink interface {
+	OnRetire(rec RetireRecord)
+}
+
+// NopRVFISink discards every retire record. It is the default sink so
+// RVI32System can always call into one without a nil check.
+type NopRVFISink struct{}
+
+func (NopRVFISink) OnRetire(rec RetireRecord) {}
@@ -0,0 +1,223 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Tracer receives pipeline trace events in place of the ad-hoc fmt.Print*
+// calls scattered across InstructionFetchStage, MemoryAccessStage, and
+// WriteBackStage, so the simulator can be made quiet (NopTracer), dumped as
+// JSON lines for diffing against spike/qemu logs (JSONLTracer), or kept
+// as a rolling post-mortem buffer (RingTracer) without recompiling.
+type Tracer interface {
+	Printf(format string, args ...any)
+	OnFetch(pc uint32, instr uint32)
+	OnDecode(dv DecodedValues)
+	OnExecute(ev ExecutedValues)
+	OnMemAccess(addr uint32, value uint32, width byte, isWrite bool)
+	OnWriteBack(rd byte, value uint32)
+	OnFlush(reason string)
+	OnRetire(rec RetireRecord)
+}
+
+// NopTracer discards every event. It is the default tracer so stages can
+// always call into one without a nil check.
+type NopTracer struct{}
+
+func (NopTracer) Printf(format string, args ...any)                               {}
+func (NopTracer) OnFetch(pc uint32, instr uint32)                                 {}
+func (NopTracer) OnDecode(dv DecodedValues)                                       {}
+func (NopTracer) OnExecute(ev ExecutedValues)                                     {}
+func (NopTracer) OnMemAccess(addr uint32, value uint32, width byte, isWrite bool) {}
+func (NopTracer) OnWriteBack(rd byte, value uint32)                               {}
+func (NopTracer) OnFlush(reason string)                                           {}
+func (NopTracer) OnRetire(rec RetireRecord)                                       {}
+
+// TextTracer reproduces the simulator's original human-readable console
+// output, plus a disassembly of the fetched instruction via Decode.
+type TextTracer struct {
+	w io.Writer
+}
+
+func NewTextTracer(w io.Writer) *TextTracer {
+	return &TextTracer{w: w}
+}
+
+func (t *TextTracer) Printf(format string, args ...any) {
+	fmt.Fprintf(t.w, format, args...)
+}
+
+func (t *TextTracer) OnFetch(pc uint32, instr uint32) {
+	fmt.Fprintf(t.w, " fetch pc=0x%08X insn=0x%08X\n", pc, instr)
+}
+
+func (t *TextTracer) OnDecode(dv DecodedValues) {
+	fmt.Fprintf(t.w, " decode insn=0x%08X opcode=0x%02X rd=R%02d\n", dv.insn, dv.opcode, dv.rd)
+}
+
+func (t *TextTracer) OnExecute(ev ExecutedValues) {
+	fmt.Fprintf(t.w, " execute rd=R%02d result=0x%08X\n", ev.rd, ev.writeBackValue)
+}
+
+func (t *TextTracer) OnWriteBack(rd byte, value uint32) {
+	fmt.Fprintf(t.w, " writeback R%02d=0x%08X\n", rd, value)
+}
+
+func (t *TextTracer) OnFlush(reason string) {
+	fmt.Fprintf(t.w, " flush: %s\n", reason)
+}
+
+func (t *TextTracer) OnRetire(rec RetireRecord) {
+	disasm := "?"
+	if ins := Decode(rec.Insn); ins != nil {
+		disasm = ins.String()
+	}
+	fmt.Fprintf(t.w, "[%d] pc=0x%08X insn=0x%08X  %s\n", rec.Order, rec.PcRdata, rec.Insn, disasm)
+}
+
+func (t *TextTracer) OnMemAccess(addr uint32, value uint32, width byte, isWrite bool) {
+	dir := "R"
+	if isWrite {
+		dir = "W"
+	}
+	fmt.Fprintf(t.w, " mem[%s] addr=0x%08X width=%d value=0x%08X\n", dir, addr, width, value)
+}
+
+// JSONLTracer emits one JSON object per line per retired instruction and
+// per memory access, suitable for diffing against a Sail/Spike/qemu trace.
+type JSONLTracer struct {
+	enc *json.Encoder
+}
+
+func NewJSONLTracer(w io.Writer) *JSONLTracer {
+	return &JSONLTracer{enc: json.NewEncoder(w)}
+}
+
+func (t *JSONLTracer) Printf(format string, args ...any) {}
+
+func (t *JSONLTracer) OnFetch(pc uint32, instr uint32) {
+	t.enc.Encode(struct {
+		Event string `json:"event"`
+		Pc    uint32 `json:"pc"`
+		Insn  uint32 `json:"insn"`
+	}{"fetch", pc, instr})
+}
+
+func (t *JSONLTracer) OnDecode(dv DecodedValues) {
+	t.enc.Encode(struct {
+		Event string        `json:"event"`
+		Dv    DecodedValues `json:"decoded"`
+	}{"decode", dv})
+}
+
+func (t *JSONLTracer) OnExecute(ev ExecutedValues) {
+	t.enc.Encode(struct {
+		Event string         `json:"event"`
+		Ev    ExecutedValues `json:"executed"`
+	}{"execute", ev})
+}
+
+func (t *JSONLTracer) OnWriteBack(rd byte, value uint32) {
+	t.enc.Encode(struct {
+		Event string `json:"event"`
+		Rd    byte   `json:"rd"`
+		Value uint32 `json:"value"`
+	}{"writeback", rd, value})
+}
+
+func (t *JSONLTracer) OnFlush(reason string) {
+	t.enc.Encode(struct {
+		Event  string `json:"event"`
+		Reason string `json:"reason"`
+	}{"flush", reason})
+}
+
+func (t *JSONLTracer) OnRetire(rec RetireRecord) {
+	t.enc.Encode(rec)
+}
+
+func (t *JSONLTracer) OnMemAccess(addr uint32, value uint32, width byte, isWrite bool) {
+	t.enc.Encode(struct {
+		Addr    uint32 `json:"addr"`
+		Value   uint32 `json:"value"`
+		Width   byte   `json:"width"`
+		IsWrite bool   `json:"is_write"`
+	}{addr, value, width, isWrite})
+}
+
+// RingTracer keeps only the last N textual events, for a post-mortem dump
+// on panic or trap instead of scrolling the whole run's output.
+type RingTracer struct {
+	events []string
+	cap    int
+	next   int
+	full   bool
+}
+
+func NewRingTracer(capacity int) *RingTracer {
+	return &RingTracer{events: make([]string, capacity), cap: capacity}
+}
+
+func (t *RingTracer) push(s string) {
+	if t.cap == 0 {
+		return
+	}
+	t.events[t.next] = s
+	t.next = (t.next + 1) % t.cap
+	if t.next == 0 {
+		t.full = true
+	}
+}
+
+func (t *RingTracer) Printf(format string, args ...any) {
+	t.push(fmt.Sprintf(format, args...))
+}
+
+func (t *RingTracer) OnFetch(pc uint32, instr uint32) {
+	t.push(fmt.Sprintf(" fetch pc=0x%08X insn=0x%08X", pc, instr))
+}
+
+func (t *RingTracer) OnDecode(dv DecodedValues) {
+	t.push(fmt.Sprintf(" decode insn=0x%08X opcode=0x%02X rd=R%02d", dv.insn, dv.opcode, dv.rd))
+}
+
+func (t *RingTracer) OnExecute(ev ExecutedValues) {
+	t.push(fmt.Sprintf(" execute rd=R%02d result=0x%08X", ev.rd, ev.writeBackValue))
+}
+
+func (t *RingTracer) OnWriteBack(rd byte, value uint32) {
+	t.push(fmt.Sprintf(" writeback R%02d=0x%08X", rd, value))
+}
+
+func (t *RingTracer) OnFlush(reason string) {
+	t.push(fmt.Sprintf(" flush: %s", reason))
+}
+
+func (t *RingTracer) OnRetire(rec RetireRecord) {
+	disasm := "?"
+	if ins := Decode(rec.Insn); ins != nil {
+		disasm = ins.String()
+	}
+	t.push(fmt.Sprintf("[%d] pc=0x%08X insn=0x%08X  %s", rec.Order, rec.PcRdata, rec.Insn, disasm))
+}
+
+func (t *RingTracer) OnMemAccess(addr uint32, value uint32, width byte, isWrite bool) {
+	dir := "R"
+	if isWrite {
+		dir = "W"
+	}
+	t.push(fmt.Sprintf(" mem[%s] addr=0x%08X width=%d value=0x%08X", dir, addr, width, value))
+}
+
+// Dump returns the buffered events in chronological order, oldest first.
+func (t *RingTracer) Dump() []string {
+	if !t.full {
+		return append([]string(nil), t.events[:t.next]...)
+	}
+	out := make([]string, 0, t.cap)
+	out = append(out, t.events[t.next:]...)
+	out = append(out, t.events[:t.next]...)
+	return out
+}
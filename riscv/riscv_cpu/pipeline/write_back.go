@@ -1,28 +1,44 @@
 package pipeline
 
 import (
-	"fmt"
 	. "riscv/system_interface"
 )
 
 type WriteBackParams struct {
 	regFile                 *[32]RUint32
 	shouldStall             func() bool
-	getMemoryAccessValuesIn func() ExecutedValues
+	getMemoryAccessValuesIn func() MemoryAccessValues
+	rvfiSink                RVFISink
 }
 
-func NewWriteBackParams(regFile *[32]RUint32, shouldStall func() bool, getMemoryAccessValuesIn func() ExecutedValues) *WriteBackParams {
+func NewWriteBackParams(regFile *[32]RUint32, shouldStall func() bool, getMemoryAccessValuesIn func() MemoryAccessValues) *WriteBackParams {
 	return &WriteBackParams{
 		regFile:                 regFile,
 		shouldStall:             shouldStall,
 		getMemoryAccessValuesIn: getMemoryAccessValuesIn,
+		rvfiSink:                NopRVFISink{},
 	}
 }
 
 type WriteBackStage struct {
 	regFile                 *[32]RUint32
 	shouldStall             func() bool
-	getMemoryAccessValuesIn func() ExecutedValues
+	getMemoryAccessValuesIn func() MemoryAccessValues
+
+	rvfiSink  RVFISink
+	rvfiOrder uint64
+
+	tracer Tracer
+
+	// lastRd/lastValue/lastValid expose this Compute call's commit for
+	// GetForwardOut, the same role MA's rd/writeBackValue/writeBackValueValid
+	// latches play for its own forward source — plain fields, not RBool/
+	// RByte/RUint32, since nothing downstream needs them to survive a
+	// LatchNext: ExecuteStage reads them the same cycle WB computes them,
+	// Compute call order in cpu.go guarantees WB runs first.
+	lastRd    byte
+	lastValue uint32
+	lastValid bool
 }
 
 func NewWriteBackStage(params *WriteBackParams) *WriteBackStage {
@@ -32,23 +48,89 @@ func NewWriteBackStage(params *WriteBackParams) *WriteBackStage {
 	ma.regFile = params.regFile
 	ma.shouldStall = params.shouldStall
 	ma.getMemoryAccessValuesIn = params.getMemoryAccessValuesIn
+	ma.rvfiSink = params.rvfiSink
+	if ma.rvfiSink == nil {
+		ma.rvfiSink = NopRVFISink{}
+	}
+	ma.tracer = NopTracer{}
 	return ma
 }
 
+// SetRVFISink attaches a sink that receives one RetireRecord per committed
+// instruction. Pass nil to go back to discarding retire traffic.
+func (ma *WriteBackStage) SetRVFISink(sink RVFISink) {
+	if sink == nil {
+		sink = NopRVFISink{}
+	}
+	ma.rvfiSink = sink
+}
+
+// SetTracer attaches a Tracer to receive this stage's trace output. Pass
+// nil to go back to discarding it.
+func (ma *WriteBackStage) SetTracer(tracer Tracer) {
+	if tracer == nil {
+		tracer = NopTracer{}
+	}
+	ma.tracer = tracer
+}
+
 func (ma *WriteBackStage) Compute() {
+	ma.lastRd = 0
+	ma.lastValue = 0
+	ma.lastValid = false
+
 	if !ma.shouldStall() {
 		memoryAccessValues := ma.getMemoryAccessValuesIn()
-		if memoryAccessValues.isAluOperation || memoryAccessValues.isLoadOperation {
+		committed := false
+		if memoryAccessValues.writeBackValid {
 			// Write-back to register file (x0 is hardwired zero)
 			if ma.regFile != nil && memoryAccessValues.rd != 0 {
 				ma.regFile[memoryAccessValues.rd].SetN(memoryAccessValues.writeBackValue)
-				fmt.Println()
+				committed = true
+				ma.lastRd = memoryAccessValues.rd
+				ma.lastValue = memoryAccessValues.writeBackValue
+				ma.lastValid = true
+				ma.tracer.Printf("\n")
+				ma.tracer.OnWriteBack(memoryAccessValues.rd, memoryAccessValues.writeBackValue)
 			} else {
-				fmt.Print(" (discarded)\n")
+				ma.tracer.Printf(" (discarded)\n")
 			}
 		}
+
+		var rec RetireRecord
+		rec.Order = ma.rvfiOrder
+		rec.Insn = memoryAccessValues.insn
+		rec.Rs1Addr = memoryAccessValues.rs1Addr
+		rec.Rs2Addr = memoryAccessValues.rs2Addr
+		rec.Rs1Rdata = memoryAccessValues.rs1V
+		rec.Rs2Rdata = memoryAccessValues.rs2V
+		rec.PcRdata = memoryAccessValues.pc
+		rec.PcWdata = memoryAccessValues.pcNext
+		if committed {
+			rec.RdAddr = memoryAccessValues.rd
+			rec.RdWdata = memoryAccessValues.writeBackValue
+		}
+		if memoryAccessValues.memRmask != 0 || memoryAccessValues.memWmask != 0 {
+			rec.MemAddr = memoryAccessValues.memAddr
+			rec.MemRmask = memoryAccessValues.memRmask
+			rec.MemWmask = memoryAccessValues.memWmask
+			rec.MemRdata = memoryAccessValues.memRdata
+			rec.MemWdata = memoryAccessValues.memWdata
+		}
+		ma.rvfiSink.OnRetire(rec)
+		ma.tracer.OnRetire(rec)
+		ma.rvfiOrder++
 	}
 }
 
 func (ma *WriteBackStage) LatchNext() {
 }
+
+// GetForwardOut exposes the register this Compute call committed (still the
+// same cycle's write, via regFile[rd].SetN — not yet latched by
+// RUint32.LatchNext) so ExecuteStage can bypass an instruction two slots
+// behind the producer, the same role MA.GetForwardOut plays for the
+// one-slot-behind case.
+func (ma *WriteBackStage) GetForwardOut() (rd byte, value uint32, valid bool) {
+	return ma.lastRd, ma.lastValue, ma.lastValid
+}
@@ -1,62 +1,193 @@
 package systeminterface
 
 import (
+	"errors"
 	"fmt"
 )
 
+// ErrMisalignedAccess marks a load/store error as a misaligned-address
+// fault rather than an access to unmapped memory, so cpu.go's
+// handleMemFault can tell the two apart with errors.Is and raise the
+// matching CSR cause (CauseLoad/StoreAddressMisaligned vs.
+// CauseLoad/StoreAccessFault). RAM_Device/ROM_Device wrap it into their
+// "Unaligned ... access" errors; every other bus error (no device mapped,
+// no register at that offset) is left unwrapped and counts as an access
+// fault.
+var ErrMisalignedAccess = errors.New("misaligned memory access")
+
+// MEMORY_WIDTH selects how many bytes a bus transaction touches.
+type MEMORY_WIDTH byte
+
+const (
+	MEMORY_WIDTH_BYTE MEMORY_WIDTH = 1
+	MEMORY_WIDTH_HALF MEMORY_WIDTH = 2
+	MEMORY_WIDTH_WORD MEMORY_WIDTH = 4
+)
+
 const (
 	MEMORY_MAP_ROM_START = 0x10000000
 	MEMORY_MAP_ROM_END   = 0x1FFFFFFF
 	MEMORY_MAP_RAM_START = 0x20000000
 	MEMORY_MAP_RAM_END   = 0x2FFFFFFF
+
+	MEMORY_MAP_UART_START = 0x30000000
+	MEMORY_MAP_UART_SIZE  = 0x100
+
+	MEMORY_MAP_TIMER_START = 0x30001000
+	MEMORY_MAP_TIMER_SIZE  = 0x100
 )
 
 type MMIO_DEVICE interface {
-	Read(addr uint64) (uint64, error)
-	Write(addr uint64, value uint64) error
+	Read(addr uint32, width MEMORY_WIDTH) (uint32, error)
+	Write(addr uint32, value uint32, width MEMORY_WIDTH) error
 }
 
+// MMIORegion is one entry of the bus's device table: a name for
+// diagnostics/SetLatency lookup, the [BaseAddr, BaseAddr+Size) range it
+// claims, the device itself, and how many extra cycles a fresh transaction
+// against it costs, the way the DMD 5620 emulator's Bus::get_device maps a
+// flat address space onto whichever device owns a given region.
+type MMIORegion struct {
+	Name     string
+	BaseAddr uint32
+	Size     uint32
+	Device   MMIO_DEVICE
+	Latency  int
+
+	// pending* track one in-flight multi-cycle transaction against THIS
+	// region. Kept per-region rather than bus-wide so InstructionFetchStage
+	// (normally reading ROM) and MemoryAccessStage (normally reading/
+	// writing RAM) can each have a transaction in flight in the same
+	// cycle, the way a real CPU's separate instruction and data ports
+	// don't contend for the same latency countdown.
+	pending           bool
+	pendingAddr       uint32
+	pendingIsWrite    bool
+	pendingCyclesLeft int
+}
+
+func (r *MMIORegion) contains(addr uint32) bool {
+	return addr >= r.BaseAddr && addr < r.BaseAddr+r.Size
+}
+
+// SystemInterface is the RV32 system bus. Read/Write report readiness in
+// addition to a value/error so InstructionFetchStage and MemoryAccessStage
+// can model a multi-cycle bus transaction instead of assuming every access
+// completes in a single cycle, the same stall-on-memory-latency behavior
+// the Lanai CPU refactor added.
 type SystemInterface struct {
-	rom *ROM_Device
-	ram *RAM_Device
+	regions []*MMIORegion
+
+	readWatchpoints  []Watchpoint
+	writeWatchpoints []Watchpoint
 }
 
+// NewSystemInterface wires up the two devices every RVI32System starts
+// with. Callers that need more peripherals register them afterward with
+// RegisterDevice.
 func NewSystemInterface(rom *ROM_Device, ram *RAM_Device) *SystemInterface {
 	si := &SystemInterface{}
-	si.rom = rom
-	si.ram = ram
+	si.RegisterDevice("ROM", MEMORY_MAP_ROM_START, MEMORY_MAP_ROM_END-MEMORY_MAP_ROM_START+1, rom)
+	si.RegisterDevice("RAM", MEMORY_MAP_RAM_START, MEMORY_MAP_RAM_END-MEMORY_MAP_RAM_START+1, ram)
 	return si
 }
 
-func (si *SystemInterface) Read(addr uint64) (uint64, error) {
-	if (addr & 0b11) != 0 {
-		return 0, fmt.Errorf("Unaligned read at address 0x%X",
-			ToHexString(addr, 32))
+// RegisterDevice maps device onto [baseAddr, baseAddr+size) of the bus.
+// Regions must not overlap; Read/Write try them in registration order.
+func (si *SystemInterface) RegisterDevice(name string, baseAddr uint32, size uint32, device MMIO_DEVICE) {
+	si.regions = append(si.regions, &MMIORegion{
+		Name:     name,
+		BaseAddr: baseAddr,
+		Size:     size,
+		Device:   device,
+	})
+}
+
+// findRegion returns the region claiming addr, if any.
+func (si *SystemInterface) findRegion(addr uint32) (*MMIORegion, bool) {
+	for _, r := range si.regions {
+		if r.contains(addr) {
+			return r, true
+		}
 	}
+	return nil, false
+}
 
-	wordAddr := (addr & 0x0FFFFFFF) >> 2 // word address
+// findRegionByName looks a region up by the name it was registered under,
+// for SetLatency to configure ROM/RAM without exposing the regions slice.
+func (si *SystemInterface) findRegionByName(name string) (*MMIORegion, bool) {
+	for _, r := range si.regions {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return nil, false
+}
 
-	if (addr & MEMORY_MAP_ROM_START) == MEMORY_MAP_ROM_START {
-		return si.rom.Read(wordAddr)
+// SetLatency configures how many extra cycles a fresh ROM/RAM transaction
+// takes to become ready, so tests can characterize hazard/stall behavior
+// against a non-trivial memory.
+func (si *SystemInterface) SetLatency(romCycles, ramCycles int) {
+	if r, ok := si.findRegionByName("ROM"); ok {
+		r.Latency = romCycles
 	}
-	if (addr & MEMORY_MAP_RAM_START) == MEMORY_MAP_RAM_START {
-		return si.ram.Read(wordAddr)
+	if r, ok := si.findRegionByName("RAM"); ok {
+		r.Latency = ramCycles
 	}
-
-	return 0, nil
 }
 
-func (si *SystemInterface) Write(addr uint64, value uint64) error {
-	if (addr & 0b11) != 0 {
-		return fmt.Errorf("Unaligned write at address 0x%X (value 0x%X)",
-			ToHexString(addr, 32), ToHexString(value, 32))
+// wait models one in-flight transaction against r: the first call for a
+// given (addr, isWrite) pair arms the countdown, subsequent calls for the
+// same transaction decrement it until it reaches zero, at which point the
+// caller may perform the actual access. A call for a different address
+// always restarts the countdown, i.e. transactions cannot be pipelined
+// within the same region.
+func (r *MMIORegion) wait(addr uint32, isWrite bool, latency int) bool {
+	if latency <= 0 {
+		return true
 	}
+	if !r.pending || r.pendingAddr != addr || r.pendingIsWrite != isWrite {
+		r.pending = true
+		r.pendingAddr = addr
+		r.pendingIsWrite = isWrite
+		r.pendingCyclesLeft = latency
+	} else {
+		r.pendingCyclesLeft--
+	}
+	if r.pendingCyclesLeft > 0 {
+		return false
+	}
+	r.pending = false
+	return true
+}
 
-	wordAddr := (addr & 0x0FFFFFFF) >> 2 // word address
-
-	if (addr & MEMORY_MAP_RAM_START) == MEMORY_MAP_RAM_START {
-		return si.ram.Write(wordAddr, value)
+// Read returns (value, ready, err). ready is false while a multi-cycle
+// transaction armed via SetLatency is still in flight; the caller must
+// keep calling Read with the same address and width until ready is true.
+func (si *SystemInterface) Read(addr uint32, width MEMORY_WIDTH) (uint32, bool, error) {
+	r, ok := si.findRegion(addr)
+	if !ok {
+		return 0, true, fmt.Errorf("no device mapped at address 0x%X", addr)
+	}
+	if !r.wait(addr, false, r.Latency) {
+		return 0, false, nil
+	}
+	v, err := r.Device.Read(addr-r.BaseAddr, width)
+	if err == nil {
+		si.checkReadWatchpoints(addr, v)
 	}
+	return v, true, err
+}
 
-	return nil
+// Write returns (ready, err) with the same back-pressure semantics as Read.
+func (si *SystemInterface) Write(addr uint32, value uint32, width MEMORY_WIDTH) (bool, error) {
+	r, ok := si.findRegion(addr)
+	if !ok {
+		return true, fmt.Errorf("no device mapped at address 0x%X", addr)
+	}
+	if !r.wait(addr, true, r.Latency) {
+		return false, nil
+	}
+	si.checkWriteWatchpoints(addr, value)
+	return true, r.Device.Write(addr-r.BaseAddr, value, width)
 }
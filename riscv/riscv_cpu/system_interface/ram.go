@@ -1,6 +1,10 @@
 package systeminterface
 
-import "fmt"
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
 
 const RAM_SIZE = 1024 * 1024 * 4 / 4 // 4 MB = 1024 * 1024 * 4 bytes = 1M int32
 const RAM_MASK = RAM_SIZE - 1
@@ -9,6 +13,18 @@ type RAM_Device struct {
 	memory [RAM_SIZE]uint32
 }
 
+// Serialize writes the full RAM contents word-by-word. Callers that need a
+// compact snapshot should gzip-wrap w, since most of a freshly booted
+// machine's RAM is zero.
+func (rd *RAM_Device) Serialize(w io.Writer) error {
+	return binary.Write(w, binary.LittleEndian, rd.memory[:])
+}
+
+// Deserialize restores RAM contents written by Serialize.
+func (rd *RAM_Device) Deserialize(r io.Reader) error {
+	return binary.Read(r, binary.LittleEndian, rd.memory[:])
+}
+
 func (rd *RAM_Device) Read(addr uint32, width MEMORY_WIDTH) (uint32, error) {
 
 	offset := addr & 0b11
@@ -34,11 +50,11 @@ func (rd *RAM_Device) Read(addr uint32, width MEMORY_WIDTH) (uint32, error) {
 		case 2:
 			value = value & 0xFFFF
 		default:
-			return 0, fmt.Errorf("Unaligned halfword read at address 0x%X", addr)
+			return 0, fmt.Errorf("%w: unaligned halfword read at address 0x%X", ErrMisalignedAccess, addr)
 		}
 	case MEMORY_WIDTH_WORD:
 		if offset != 0 {
-			return 0, fmt.Errorf("Unaligned word read at address 0x%X", addr)
+			return 0, fmt.Errorf("%w: unaligned word read at address 0x%X", ErrMisalignedAccess, addr)
 		}
 	}
 
@@ -71,13 +87,13 @@ func (rd *RAM_Device) Write(addr uint32, value uint32, width MEMORY_WIDTH) error
 			case 2:
 				rd.memory[maskedAddr] = ((currentValue & 0xFFFF0000) | (value & 0xFFFF))
 			default:
-				return fmt.Errorf("Unaligned halfword write at address 0x%X", addr)
+				return fmt.Errorf("%w: unaligned halfword write at address 0x%X", ErrMisalignedAccess, addr)
 			}
 		}
 	case MEMORY_WIDTH_WORD:
 		{
 			if offset != 0 {
-				return fmt.Errorf("Unaligned word write at address 0x%X", addr)
+				return fmt.Errorf("%w: unaligned word write at address 0x%X", ErrMisalignedAccess, addr)
 			}
 			rd.memory[maskedAddr] = (value & 0xFFFFFFFF)
 		}
@@ -1,5 +1,10 @@
 package systeminterface
 
+import (
+	"encoding/binary"
+	"io"
+)
+
 type RUint32 struct {
 	value     uint32
 	valueNext uint32
@@ -25,6 +30,23 @@ func (r *RUint32) LatchNext() {
 	r.value = r.valueNext
 }
 
+// Serialize writes both the latched value and the pending next value, in
+// that order, so a snapshot can resume mid-cycle.
+func (r *RUint32) Serialize(w io.Writer) error {
+	if err := binary.Write(w, binary.LittleEndian, r.value); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, r.valueNext)
+}
+
+// Deserialize restores value and valueNext written by Serialize.
+func (r *RUint32) Deserialize(reader io.Reader) error {
+	if err := binary.Read(reader, binary.LittleEndian, &r.value); err != nil {
+		return err
+	}
+	return binary.Read(reader, binary.LittleEndian, &r.valueNext)
+}
+
 //  -----------------
 
 type RInt32 struct {
@@ -52,6 +74,22 @@ func (r *RInt32) LatchNext() {
 	r.value = r.valueNext
 }
 
+// Serialize writes both the latched value and the pending next value.
+func (r *RInt32) Serialize(w io.Writer) error {
+	if err := binary.Write(w, binary.LittleEndian, r.value); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, r.valueNext)
+}
+
+// Deserialize restores value and valueNext written by Serialize.
+func (r *RInt32) Deserialize(reader io.Reader) error {
+	if err := binary.Read(reader, binary.LittleEndian, &r.value); err != nil {
+		return err
+	}
+	return binary.Read(reader, binary.LittleEndian, &r.valueNext)
+}
+
 //  -----------------
 
 type RByte struct {
@@ -78,3 +116,78 @@ func (r *RByte) SetN(value byte) {
 func (r *RByte) LatchNext() {
 	r.value = r.valueNext
 }
+
+// Serialize writes both the latched value and the pending next value.
+func (r *RByte) Serialize(w io.Writer) error {
+	if _, err := w.Write([]byte{r.value}); err != nil {
+		return err
+	}
+	_, err := w.Write([]byte{r.valueNext})
+	return err
+}
+
+// Deserialize restores value and valueNext written by Serialize.
+func (r *RByte) Deserialize(reader io.Reader) error {
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(reader, buf); err != nil {
+		return err
+	}
+	r.value = buf[0]
+	r.valueNext = buf[1]
+	return nil
+}
+
+//  -----------------
+
+type RBool struct {
+	value     bool
+	valueNext bool
+}
+
+func NewRBool(value bool) RBool {
+	return RBool{value: value, valueNext: value}
+}
+
+func (r *RBool) Get() bool {
+	return r.value
+}
+
+func (r *RBool) GetN() bool {
+	return r.valueNext
+}
+
+func (r *RBool) SetN(value bool) {
+	r.valueNext = value
+}
+
+func (r *RBool) LatchNext() {
+	r.value = r.valueNext
+}
+
+// Serialize writes both the latched value and the pending next value, one
+// byte each (0 or 1), the same two-byte layout RByte uses.
+func (r *RBool) Serialize(w io.Writer) error {
+	if _, err := w.Write([]byte{boolToByte(r.value)}); err != nil {
+		return err
+	}
+	_, err := w.Write([]byte{boolToByte(r.valueNext)})
+	return err
+}
+
+// Deserialize restores value and valueNext written by Serialize.
+func (r *RBool) Deserialize(reader io.Reader) error {
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(reader, buf); err != nil {
+		return err
+	}
+	r.value = buf[0] != 0
+	r.valueNext = buf[1] != 0
+	return nil
+}
+
+func boolToByte(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
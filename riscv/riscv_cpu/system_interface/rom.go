@@ -1,6 +1,10 @@
 package systeminterface
 
-import "fmt"
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
 
 const ROM_SIZE = 1024 * 1024 / 4 // 1 MB = 1024 * 1024 bytes = 256K int32
 const ROM_MASK = ROM_SIZE - 1
@@ -10,6 +14,22 @@ type ROM_Device struct {
 	ProgramSize uint32
 }
 
+// Serialize writes ProgramSize followed by the full ROM contents.
+func (rd *ROM_Device) Serialize(w io.Writer) error {
+	if err := binary.Write(w, binary.LittleEndian, rd.ProgramSize); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, rd.memory[:])
+}
+
+// Deserialize restores ROM state written by Serialize.
+func (rd *ROM_Device) Deserialize(r io.Reader) error {
+	if err := binary.Read(r, binary.LittleEndian, &rd.ProgramSize); err != nil {
+		return err
+	}
+	return binary.Read(r, binary.LittleEndian, rd.memory[:])
+}
+
 func (rd *ROM_Device) Read(addr uint32, width MEMORY_WIDTH) (uint32, error) {
 
 	offset := addr & 0b11
@@ -29,17 +49,17 @@ func (rd *ROM_Device) Read(addr uint32, width MEMORY_WIDTH) (uint32, error) {
 			value = value & 0xFF
 		}
 	case MEMORY_WIDTH_HALF:
-		switch offset & 1 {
+		switch offset {
 		case 0:
 			value = (value >> 16) & 0xFFFF
-		case 1:
+		case 2:
 			value = value & 0xFFFF
 		default:
-			return 0, fmt.Errorf("Unaligned halfword read at address 0x%X", addr)
+			return 0, fmt.Errorf("%w: unaligned halfword read at address 0x%X", ErrMisalignedAccess, addr)
 		}
 	case MEMORY_WIDTH_WORD:
 		if offset != 0 {
-			return 0, fmt.Errorf("Unaligned word read at address 0x%X", addr)
+			return 0, fmt.Errorf("%w: unaligned word read at address 0x%X", ErrMisalignedAccess, addr)
 		}
 	}
 
@@ -0,0 +1,76 @@
+package systeminterface
+
+import "fmt"
+
+// Timer register offsets, modeled after the RISC-V CLINT: a free-running
+// 64-bit mtime counter and a 64-bit mtimecmp compare register, each split
+// into a lo/hi word pair since every bus transaction is at most 4 bytes.
+const (
+	TIMER_REG_MTIME_LO    = 0x00
+	TIMER_REG_MTIME_HI    = 0x04
+	TIMER_REG_MTIMECMP_LO = 0x08
+	TIMER_REG_MTIMECMP_HI = 0x0C
+)
+
+// Timer_Device is a memory-mapped mtime/mtimecmp pair at
+// MEMORY_MAP_TIMER_START. Tick advances mtime by one; Pending reports the
+// level-triggered timer-interrupt line a CLINT's MTIP normally drives,
+// true whenever mtime has reached mtimecmp.
+type Timer_Device struct {
+	mtime    uint64
+	mtimecmp uint64
+}
+
+func NewTimerDevice() *Timer_Device {
+	return &Timer_Device{}
+}
+
+// Tick advances mtime by one, meant to be driven once per pipeline cycle.
+func (t *Timer_Device) Tick() {
+	t.mtime++
+}
+
+// Pending reports whether the timer interrupt line is currently asserted.
+func (t *Timer_Device) Pending() bool {
+	return t.mtime >= t.mtimecmp
+}
+
+// Mtime returns the current 64-bit timer count, for CSRFile's mirror.
+func (t *Timer_Device) Mtime() uint64 {
+	return t.mtime
+}
+
+// Mtimecmp returns the current compare value, for CSRFile's mirror.
+func (t *Timer_Device) Mtimecmp() uint64 {
+	return t.mtimecmp
+}
+
+func (t *Timer_Device) Read(addr uint32, width MEMORY_WIDTH) (uint32, error) {
+	switch addr {
+	case TIMER_REG_MTIME_LO:
+		return uint32(t.mtime), nil
+	case TIMER_REG_MTIME_HI:
+		return uint32(t.mtime >> 32), nil
+	case TIMER_REG_MTIMECMP_LO:
+		return uint32(t.mtimecmp), nil
+	case TIMER_REG_MTIMECMP_HI:
+		return uint32(t.mtimecmp >> 32), nil
+	}
+	return 0, fmt.Errorf("timer: no register at offset 0x%X", addr)
+}
+
+func (t *Timer_Device) Write(addr uint32, value uint32, width MEMORY_WIDTH) error {
+	switch addr {
+	case TIMER_REG_MTIME_LO:
+		t.mtime = (t.mtime &^ 0xFFFFFFFF) | uint64(value)
+	case TIMER_REG_MTIME_HI:
+		t.mtime = (t.mtime & 0xFFFFFFFF) | (uint64(value) << 32)
+	case TIMER_REG_MTIMECMP_LO:
+		t.mtimecmp = (t.mtimecmp &^ 0xFFFFFFFF) | uint64(value)
+	case TIMER_REG_MTIMECMP_HI:
+		t.mtimecmp = (t.mtimecmp & 0xFFFFFFFF) | (uint64(value) << 32)
+	default:
+		return fmt.Errorf("timer: no register at offset 0x%X", addr)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package systeminterface
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// UART register offsets, modeled after a 16550's THR/RBR and LSR: just
+// enough of the real register set for a guest to poll "can I send/receive"
+// before touching the data register.
+const (
+	UART_REG_DATA   = 0x00 // THR (write) / RBR (read)
+	UART_REG_STATUS = 0x04 // LSR
+)
+
+const (
+	UART_LSR_DATA_READY = 1 << 0 // RBR holds an unread byte
+	UART_LSR_THR_EMPTY  = 1 << 5 // THR can accept another byte
+)
+
+// UART_Device is a minimal 16550-style serial port mapped at
+// MEMORY_MAP_UART_START: every byte written to UART_REG_DATA goes straight
+// to Out, and UART_REG_DATA reads pull one byte off input if one is queued.
+// THR is always empty in this single-hart model, so UART_LSR_THR_EMPTY is
+// always set.
+//
+// input is a plain byte queue guarded by mu rather than an RUint32/RByte
+// latch: it's fed from outside the pipeline's Cycle loop (PushInput, or the
+// stdin pump goroutine NewUARTDevice starts), so it needs its own
+// synchronization instead of the Compute/LatchNext discipline every other
+// stage uses.
+type UART_Device struct {
+	Out io.Writer
+
+	mu    sync.Mutex
+	input []byte
+}
+
+// NewUARTDevice wires the UART's output to the host's real stdout and
+// starts a goroutine pumping stdin into its input queue, so a guest
+// polling UART_REG_STATUS never blocks Cycle the way reading directly off
+// os.Stdin would.
+func NewUARTDevice() *UART_Device {
+	u := &UART_Device{Out: os.Stdout}
+	go u.pumpStdin(os.Stdin)
+	return u
+}
+
+// pumpStdin copies r into the UART's input queue one byte at a time. It
+// runs on its own goroutine for the life of the device, off the
+// simulation goroutine, since os.Stdin.Read blocks until a byte arrives
+// and Cycle can't afford to.
+func (u *UART_Device) pumpStdin(r io.Reader) {
+	buf := make([]byte, 1)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			u.PushInput(buf[:n])
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// PushInput enqueues data as pending UART input, for a test (or an
+// embedder without a real terminal) to feed the guest without going
+// through os.Stdin.
+func (u *UART_Device) PushInput(data []byte) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.input = append(u.input, data...)
+}
+
+func (u *UART_Device) Read(addr uint32, width MEMORY_WIDTH) (uint32, error) {
+	switch addr {
+	case UART_REG_DATA:
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		if len(u.input) == 0 {
+			return 0, nil // no input pending; guest should poll UART_REG_STATUS first
+		}
+		b := u.input[0]
+		u.input = u.input[1:]
+		return uint32(b), nil
+	case UART_REG_STATUS:
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		status := uint32(UART_LSR_THR_EMPTY)
+		if len(u.input) > 0 {
+			status |= UART_LSR_DATA_READY
+		}
+		return status, nil
+	}
+	return 0, fmt.Errorf("uart: no register at offset 0x%X", addr)
+}
+
+func (u *UART_Device) Write(addr uint32, value uint32, width MEMORY_WIDTH) error {
+	switch addr {
+	case UART_REG_DATA:
+		_, err := u.Out.Write([]byte{byte(value)})
+		return err
+	case UART_REG_STATUS:
+		return nil // LSR is read-only; ignore writes the way ROM ignores them
+	}
+	return fmt.Errorf("uart: no register at offset 0x%X", addr)
+}
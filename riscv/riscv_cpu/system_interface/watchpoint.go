@@ -0,0 +1,57 @@
+package systeminterface
+
+// Watchpoint is an address+mask match against the bus, the same shape the
+// PSX cop0 debug facility's BDA/BDAM (data breakpoint address/mask) pair
+// uses: a transaction hits the watchpoint when addr&Mask == Addr&Mask, so a
+// mask of 0xFFFFFFFF matches one address and a coarser mask matches a whole
+// region. OnHit fires once the access is ready: after a read completes,
+// with the value read, and just before a write takes effect, with the
+// value about to be written.
+type Watchpoint struct {
+	Addr  uint32
+	Mask  uint32
+	OnHit func(addr uint32, value uint32, isWrite bool)
+}
+
+func (wp *Watchpoint) matches(addr uint32) bool {
+	return addr&wp.Mask == wp.Addr&wp.Mask
+}
+
+// AddReadWatchpoint registers a watchpoint fired by every Read matching
+// addr&mask == addr&mask, mirroring AddWriteWatchpoint for loads.
+func (si *SystemInterface) AddReadWatchpoint(addr uint32, mask uint32, onHit func(addr uint32, value uint32, isWrite bool)) {
+	si.readWatchpoints = append(si.readWatchpoints, Watchpoint{Addr: addr, Mask: mask, OnHit: onHit})
+}
+
+// AddWriteWatchpoint registers a watchpoint fired by every Write matching
+// addr&mask == addr&mask, before the access takes effect.
+func (si *SystemInterface) AddWriteWatchpoint(addr uint32, mask uint32, onHit func(addr uint32, value uint32, isWrite bool)) {
+	si.writeWatchpoints = append(si.writeWatchpoints, Watchpoint{Addr: addr, Mask: mask, OnHit: onHit})
+}
+
+// ClearWatchpoints removes every registered read and write watchpoint, for
+// a debugger detaching or a GDB client clearing its breakpoint set.
+func (si *SystemInterface) ClearWatchpoints() {
+	si.readWatchpoints = nil
+	si.writeWatchpoints = nil
+}
+
+// checkReadWatchpoints fires every read watchpoint matching addr, once the
+// access has actually completed (ready && err == nil).
+func (si *SystemInterface) checkReadWatchpoints(addr uint32, value uint32) {
+	for _, wp := range si.readWatchpoints {
+		if wp.matches(addr) {
+			wp.OnHit(addr, value, false)
+		}
+	}
+}
+
+// checkWriteWatchpoints fires every write watchpoint matching addr, once
+// the access is ready to actually take effect.
+func (si *SystemInterface) checkWriteWatchpoints(addr uint32, value uint32) {
+	for _, wp := range si.writeWatchpoints {
+		if wp.matches(addr) {
+			wp.OnHit(addr, value, true)
+		}
+	}
+}
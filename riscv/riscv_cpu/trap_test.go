@@ -0,0 +1,139 @@
+package riscv
+
+import (
+	"riscv/csr"
+	. "riscv/pipeline"
+	. "riscv/system_interface"
+	"testing"
+)
+
+// TestUnalignedLoadTraps builds a program that misaligns x[rs1] before an
+// LW, installs a handler via mtvec, and checks the trap was taken with the
+// misaligned-load cause and the handler ran (it leaves a marker in RAM).
+// The handler moves mepc past the faulting LW before its MRET, so
+// execution resumes at the self-loop instead of re-faulting forever.
+func TestUnalignedLoadTraps(t *testing.T) {
+	sys := NewRVI32System()
+
+	const handlerAddr = MEMORY_MAP_ROM_START + 7*4
+
+	program := []uint32{
+		LUI(1, int32(handlerAddr>>12)), // 0: x1 = handler addr
+		ADDI(1, 1, int32(handlerAddr&0xFFF)),
+		CSRRW(0, int32(csr.MTVEC), 1), // 2: mtvec = x1
+
+		LUI(3, int32(MEMORY_MAP_RAM_START>>12)), // 3: x3 = RAM_BASE
+		ADDI(3, 3, 1),                           // 4: x3 = RAM_BASE + 1 (misaligned)
+		LW(5, 3, 0),                             // 5: faults: unaligned word load
+
+		JAL(0, 0), // 6: self-loop; MRET resumes here
+
+		// handler @ 7:
+		ADDI(4, 0, 1),                 // 7: x4 = marker value
+		SW(3, 4, -1),                  // 8: RAM[RAM_BASE] = 1 (x3-1 == RAM_BASE)
+		CSRRS(10, int32(csr.MEPC), 0), // 9: x10 = mepc (faulting LW's pc)
+		ADDI(10, 10, 4),               // 10: skip past the faulting LW
+		CSRRW(0, int32(csr.MEPC), 10), // 11: mepc = x10
+		MRET(),                        // 12: resume at instruction 6
+	}
+	sys.rom.Load(program)
+
+	for i := 0; i < len(program)*6; i++ {
+		sys.Cycle()
+	}
+
+	if sys.CSRs.Mcause != csr.CauseLoadAddressMisaligned {
+		t.Fatalf("mcause = 0x%X; want CauseLoadAddressMisaligned", sys.CSRs.Mcause)
+	}
+	v, _, _ := sys.bus.Read(MEMORY_MAP_RAM_START, MEMORY_WIDTH_WORD)
+	if v != 1 {
+		t.Fatalf("RAM[0x%X] = %d; want 1 (trap handler never ran)", MEMORY_MAP_RAM_START, v)
+	}
+}
+
+// TestECALLTraps sends an a7 syscall number with no registered handler, so
+// handleTraps falls through to a real CSR trap instead of the semihosting
+// dispatch, and checks the trap cause and that the handler ran.
+func TestECALLTraps(t *testing.T) {
+	sys := NewRVI32System()
+
+	const handlerAddr = MEMORY_MAP_ROM_START + 8*4
+	const unregisteredSyscall = 2000
+
+	program := []uint32{
+		LUI(1, int32(handlerAddr>>12)), // 0: x1 = handler addr
+		ADDI(1, 1, int32(handlerAddr&0xFFF)),
+		CSRRW(0, int32(csr.MTVEC), 1), // 2: mtvec = x1
+
+		LUI(3, int32(MEMORY_MAP_RAM_START>>12)), // 3: x3 = RAM_BASE (marker addr)
+		ADDI(REG_A7, 0, unregisteredSyscall),    // 4: a7 = unregistered syscall number
+		ECALL(),                                 // 5: faults: no handler registered
+
+		JAL(0, 0), // 6: self-loop
+		JAL(0, 0), // 7: padding so handler starts word-aligned at index 8
+
+		// handler @ 8:
+		ADDI(4, 0, 1),                 // 8: x4 = marker value
+		SW(3, 4, 0),                   // 9: RAM[RAM_BASE] = 1
+		CSRRS(10, int32(csr.MEPC), 0), // 10: x10 = mepc (faulting ECALL's pc)
+		ADDI(10, 10, 4),               // 11: skip past the faulting ECALL
+		CSRRW(0, int32(csr.MEPC), 10), // 12: mepc = x10
+		MRET(),                        // 13: resume at instruction 6
+	}
+	sys.rom.Load(program)
+
+	for i := 0; i < len(program)*6; i++ {
+		sys.Cycle()
+	}
+
+	if sys.CSRs.Mcause != csr.CauseECallFromMMode {
+		t.Fatalf("mcause = 0x%X; want CauseECallFromMMode", sys.CSRs.Mcause)
+	}
+	v, _, _ := sys.bus.Read(MEMORY_MAP_RAM_START, MEMORY_WIDTH_WORD)
+	if v != 1 {
+		t.Fatalf("RAM[0x%X] = %d; want 1 (trap handler never ran)", MEMORY_MAP_RAM_START, v)
+	}
+}
+
+// TestTimerInterruptTraps arms the timer to fire immediately (mtimecmp
+// defaults to 0, so Pending is already true the instant mie.MTIE and
+// mstatus.MIE are both set) and checks the interrupt redirects fetch to
+// the handler, which leaves a marker in RAM and returns via MRET. The
+// handler does not disarm the timer, so the interrupt is taken again after
+// each return.
+func TestTimerInterruptTraps(t *testing.T) {
+	sys := NewRVI32System()
+
+	const handlerAddr = MEMORY_MAP_ROM_START + 8*4
+
+	program := []uint32{
+		LUI(1, int32(handlerAddr>>12)), // 0: x1 = handler addr
+		ADDI(1, 1, int32(handlerAddr&0xFFF)),
+		CSRRW(0, int32(csr.MTVEC), 1), // 2: mtvec = x1
+
+		LUI(7, int32(MEMORY_MAP_RAM_START>>12)),             // 3: x7 = RAM_BASE (marker addr)
+		ADDI(6, 0, int32(csr.MieMTIE)),                      // 4: x6 = mie.MTIE bit
+		CSRRW(0, int32(csr.MIE), 6),                         // 5: mie = x6
+		CSRRWI(0, int32(csr.MSTATUS), byte(csr.MstatusMIE)), // 6: mstatus.MIE = 1
+
+		JAL(0, 0), // 7: self-loop; interrupt fires before or at this fetch
+
+		// handler @ 8:
+		ADDI(4, 0, 1), // 8: x4 = marker value
+		SW(7, 4, 0),   // 9: RAM[RAM_BASE] = 1
+		MRET(),        // 10: resume where the interrupt preempted fetch
+	}
+	sys.rom.Load(program)
+
+	for i := 0; i < len(program)*6; i++ {
+		sys.Cycle()
+	}
+
+	if sys.CSRs.Mcause != csr.CauseMachineTimerInterrupt {
+		t.Fatalf("mcause = 0x%X; want CauseMachineTimerInterrupt", sys.CSRs.Mcause)
+	}
+	v, _, _ := sys.bus.Read(MEMORY_MAP_RAM_START, MEMORY_WIDTH_WORD)
+	if v != 1 {
+		t.Fatalf("RAM[0x%X] = %d; want 1 (trap handler never ran)", MEMORY_MAP_RAM_START, v)
+	}
+}